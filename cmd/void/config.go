@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/void-shell/void/internal/config"
+	"github.com/void-shell/void/internal/theme"
+)
+
+const configUsage = `usage: void config check [file]
+       void config show [--origin]
+       void config get <key>
+       void config set <key> <value>
+       void config unset <key>
+       void config edit
+       void config diff`
+
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 1
+	}
+	switch args[0] {
+	case "check":
+		return runConfigCheck(args[1:])
+	case "show":
+		return runConfigShow(args[1:])
+	case "get":
+		return runConfigGet(args[1:])
+	case "set":
+		return runConfigSet(args[1:])
+	case "unset":
+		return runConfigUnset(args[1:])
+	case "edit":
+		return runConfigEdit(args[1:])
+	case "diff":
+		return runConfigDiff(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 1
+	}
+}
+
+// runConfigCheck validates a config file and its preset without starting the
+// shell. Problems are printed as file:line:column so editors can jump to them.
+func runConfigCheck(args []string) int {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	path := *configPath
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	var cfg config.Config
+	var files []string
+	if path != "" {
+		var err error
+		if cfg, err = config.LoadFile(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		files = []string{path}
+	} else {
+		dir, _ := os.Getwd()
+		r, err := config.LoadLayers("", dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		cfg = r.Config
+		for _, w := range r.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+		for _, f := range r.Files {
+			files = append(files, f.Path)
+		}
+	}
+	if _, err := theme.ApplyPreset(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "preset %q: %v\n", cfg.Preset, err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Println("no config file found; defaults are valid")
+		return 0
+	}
+	for _, f := range files {
+		fmt.Printf("%s: ok\n", f)
+	}
+	return 0
+}
+
+// runConfigShow prints the effective value of every key after all layers are
+// merged, optionally with the layer that set it.
+func runConfigShow(args []string) int {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	origin := fs.Bool("origin", false, "Show which layer set each value")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	dir, _ := os.Getwd()
+	r, err := config.LoadLayers(*configPath, dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, key := range config.Keys(r.Config) {
+		value, _ := config.Get(r.Config, key)
+		line := key + " = " + config.FormatValue(value)
+		if *origin {
+			line += "  # " + r.Origins[key].String()
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+func runConfigGet(args []string) int {
+	fs := flag.NewFlagSet("config get", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: void config get <key>")
+		return 1
+	}
+	dir, _ := os.Getwd()
+	r, err := config.LoadLayers(*configPath, dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	value, ok := config.Get(r.Config, fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "void: config: no value for %q\n", fs.Arg(0))
+		return 1
+	}
+	if s, ok := value.(string); ok {
+		fmt.Println(s)
+	} else {
+		fmt.Println(config.FormatValue(value))
+	}
+	return 0
+}
+
+// runConfigSet writes one key to the user config file, keeping the rest of
+// the file as it was.
+func runConfigSet(args []string) int {
+	fs := flag.NewFlagSet("config set", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "usage: void config set <key> <value>")
+		return 1
+	}
+	path, doc, err := openUserConfig(*configPath)
+	if err == nil {
+		err = doc.Set(fs.Arg(0), strings.Join(fs.Args()[1:], " "))
+	}
+	if err == nil {
+		err = doc.Save(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runConfigUnset(args []string) int {
+	fs := flag.NewFlagSet("config unset", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: void config unset <key>")
+		return 1
+	}
+	path, doc, err := openUserConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+		return 1
+	}
+	removed, err := doc.Unset(fs.Arg(0))
+	if err == nil && !removed {
+		err = fmt.Errorf("%s is not set in %s", fs.Arg(0), path)
+	}
+	if err == nil {
+		err = doc.Save(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func openUserConfig(fromFlag string) (string, *config.Document, error) {
+	path, err := config.UserFile(fromFlag)
+	if err != nil {
+		return "", nil, err
+	}
+	doc, err := config.ReadDocument(path)
+	return path, doc, err
+}
+
+// runConfigEdit opens a copy of the user config in $VISUAL or $EDITOR and
+// only replaces the file once the edited copy is valid.
+func runConfigEdit(args []string) int {
+	fs := flag.NewFlagSet("config edit", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	path, err := config.UserFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+		return 1
+	}
+	original, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+		return 1
+	}
+
+	tmp, err := os.CreateTemp("", "void-config-*.toml")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+		return 1
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(original)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+		return 1
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			fmt.Fprintf(os.Stderr, "void: config: editor: %v\n", err)
+			return 1
+		}
+		data, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+			return 1
+		}
+		if bytes.Equal(data, original) {
+			fmt.Println("no changes")
+			return 0
+		}
+		if err := config.ParseDocument(data).Save(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprint(os.Stderr, "Edit again? [Y/n] ")
+			answer, _ := stdin.ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a == "" || a == "y" || a == "yes" {
+				continue
+			}
+			fmt.Fprintln(os.Stderr, "void: config: changes discarded")
+			return 1
+		}
+		fmt.Printf("%s: saved\n", path)
+		return 0
+	}
+}
+
+var runEditor = func(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// runConfigDiff prints the effective values that differ from the built-in
+// defaults.
+func runConfigDiff(args []string) int {
+	fs := flag.NewFlagSet("config diff", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	dir, _ := os.Getwd()
+	r, err := config.LoadLayers(*configPath, dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, c := range config.Diff(r.Config) {
+		if c.Default != nil {
+			fmt.Printf("-%s = %s\n", c.Key, config.FormatValue(c.Default))
+		}
+		if c.Value != nil {
+			fmt.Printf("+%s = %s\n", c.Key, config.FormatValue(c.Value))
+		}
+	}
+	return 0
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
 	"time"
 
@@ -31,6 +32,10 @@ func main() {
 			os.Exit(runPrompt(os.Args[2:]))
 		case "init":
 			os.Exit(runInit(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case "theme":
+			os.Exit(runTheme(os.Args[2:]))
 		case "install":
 			os.Exit(runInstall(os.Args[2:]))
 		case "update":
@@ -80,6 +85,10 @@ func runPrompt(args []string) int {
 	configPath := fs.String("config", "", "Path to config file")
 	lastExitCode := fs.Int("last-exit-code", 0, "Previous command exit code")
 	workdir := fs.String("workdir", "", "Working directory")
+	duration := fs.Int("duration", 0, "Previous command duration in milliseconds")
+	columns := fs.Int("columns", 0, "Terminal width for right-aligned segments")
+	transient := fs.Bool("transient", false, "Print the transient one-line prompt, or nothing when it is off")
+	refresh := fs.Bool("refresh", false, "Finish slow segments for the cache instead of printing a prompt")
 	fs.SetOutput(os.Stderr)
 	if err := fs.Parse(args); err != nil {
 		return 1
@@ -96,14 +105,48 @@ func runPrompt(args []string) int {
 		return 1
 	}
 
-	out := prompt.Render(merged.Prompt.Segments, merged.Prompt.Symbol, merged.Palette, prompt.Context{
+	opts := prompt.OptionsFromConfig(merged)
+	opts.CacheFile = prompt.DefaultCacheFile()
+	r := prompt.New(opts)
+	if *transient {
+		fmt.Print(r.Transient())
+		return 0
+	}
+	ctx := prompt.Context{
 		LastExitCode: *lastExitCode,
 		WorkDir:      *workdir,
-	})
-	fmt.Print(out)
+		Duration:     time.Duration(*duration) * time.Millisecond,
+		Columns:      *columns,
+	}
+	if *refresh {
+		r.Render(ctx)
+		r.Wait()
+		return 0
+	}
+	// Shell hooks capture stdout as the prompt, so the notification goes
+	// straight to the terminal on stderr.
+	fmt.Fprint(os.Stderr, r.Notification(ctx))
+	fmt.Print(r.Render(ctx))
+	if r.Outdated() {
+		refreshInBackground(args)
+	}
 	return 0
 }
 
+// refreshInBackground reruns `void prompt` with --refresh in a process the
+// shell does not wait for, so slow segments that missed this prompt are
+// saved for the next one instead of dying with this process.
+func refreshInBackground(args []string) {
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(exe, append([]string{"prompt", "--refresh"}, args...)...)
+	if cmd.Start() == nil {
+		cmd.Process.Release()
+	}
+}
+
 func runInit(args []string) int {
 	if len(args) != 1 {
 		fmt.Fprintln(os.Stderr, "usage: void init <powershell|bash|zsh|cmd>")
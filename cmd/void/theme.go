@@ -0,0 +1,223 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/void-shell/void/internal/config"
+	"github.com/void-shell/void/internal/lineedit"
+	"github.com/void-shell/void/internal/prompt"
+	"github.com/void-shell/void/internal/theme"
+)
+
+// previewTimeout is how long a theme preview waits for git and runtime
+// versions, so it shows them rather than their placeholder.
+const previewTimeout = 10 * time.Second
+
+const themeUsage = `usage: void theme list
+       void theme pick
+       void theme show <name>
+       void theme export [--output file] [name]`
+
+func runTheme(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, themeUsage)
+		return 1
+	}
+	switch args[0] {
+	case "list":
+		return runThemeList()
+	case "pick":
+		return runThemePick(args[1:])
+	case "show":
+		return runThemeShow(args[1:])
+	case "export":
+		return runThemeExport(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, themeUsage)
+		return 1
+	}
+}
+
+// runThemeList prints every built-in and user theme, marking the active one.
+func runThemeList() int {
+	cfg, _, err := config.Load("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: failed to load config: %v\n", err)
+		return 1
+	}
+	for _, name := range theme.Names() {
+		marker := " "
+		if name == cfg.Preset {
+			marker = "*"
+		}
+		t, err := theme.Load(name)
+		switch {
+		case err != nil:
+			fmt.Printf("%s %-12s  error: %v\n", marker, name, err)
+		case t.Path == "":
+			fmt.Printf("%s %-12s  built-in\n", marker, name)
+		case t.Extends != "":
+			fmt.Printf("%s %-12s  %s (extends %s)\n", marker, name, t.Path, t.Extends)
+		default:
+			fmt.Printf("%s %-12s  %s\n", marker, name, t.Path)
+		}
+	}
+	return 0
+}
+
+// runThemeShow renders sample prompts in the current directory with the
+// theme applied, once after a successful command and once after a failure.
+func runThemeShow(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: void theme show <name>")
+		return 1
+	}
+	t, err := theme.Load(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: theme: %v\n", err)
+		return 1
+	}
+	cfg, _, err := config.Load("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: failed to load config: %v\n", err)
+		return 1
+	}
+	wd, _ := os.Getwd()
+	p := &previewer{cfg: cfg, dir: wd}
+	for _, code := range []int{0, 1} {
+		fmt.Println(p.render(t, code))
+	}
+	return 0
+}
+
+// previewer renders sample prompts for themes on top of the loaded config,
+// so previews keep the user's segment styles, colour mode and right
+// segments. The previews share one renderer cache and git runs once for
+// all of them.
+type previewer struct {
+	cfg config.Config
+	dir string
+	r   *prompt.Renderer
+}
+
+// render returns the prompt t would show after a command that exited with
+// code.
+func (p *previewer) render(t theme.Theme, code int) string {
+	opts := prompt.OptionsFromConfig(t.Apply(p.cfg))
+	opts.Timeout = previewTimeout
+	if p.r == nil {
+		p.r = prompt.New(opts)
+	} else {
+		p.r = p.r.With(opts)
+	}
+	return p.r.Render(prompt.Context{LastExitCode: code, WorkDir: p.dir})
+}
+
+// runThemePick previews every theme against the current directory and saves
+// the chosen one as the preset in the user config file.
+func runThemePick(args []string) int {
+	fs := flag.NewFlagSet("theme pick", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	cfg, _, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: failed to load config: %v\n", err)
+		return 1
+	}
+
+	wd, _ := os.Getwd()
+	p := &previewer{cfg: cfg, dir: wd}
+	names := theme.Names()
+	choices := make([]lineedit.Choice, len(names))
+	selected := 0
+	for i, name := range names {
+		label := name
+		if name == cfg.Preset {
+			label += " (current)"
+			selected = i
+		}
+		t, err := theme.Load(name)
+		if err != nil {
+			choices[i] = lineedit.Choice{Label: label, Preview: fmt.Sprintf("error: %v", err)}
+			continue
+		}
+		choices[i] = lineedit.Choice{Label: label, Preview: p.render(t, 0)}
+	}
+
+	editor := lineedit.New(os.Stdin, os.Stdout)
+	choice, err := editor.Pick("Pick a theme (Enter to save, Esc to cancel)", choices, selected)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: theme pick: %v\n", err)
+		return 1
+	}
+	if choice < 0 {
+		return 0
+	}
+	name := names[choice]
+	if _, err := theme.Load(name); err != nil {
+		fmt.Fprintf(os.Stderr, "void: theme: %v\n", err)
+		return 1
+	}
+	path, doc, err := openUserConfig(*configPath)
+	if err == nil {
+		err = doc.Set("preset", name)
+	}
+	if err == nil {
+		err = doc.Save(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: config: %v\n", err)
+		return 1
+	}
+	fmt.Printf("preset = %q saved to %s\n", name, path)
+	return 0
+}
+
+// runThemeExport writes a theme as one self-contained file. Without a name
+// it exports the prompt settings currently in effect.
+func runThemeExport(args []string) int {
+	fs := flag.NewFlagSet("theme export", flag.ContinueOnError)
+	output := fs.String("output", "", "Write the theme to this file instead of stdout")
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	var t theme.Theme
+	if fs.NArg() > 0 {
+		var err error
+		if t, err = theme.Load(fs.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "void: theme: %v\n", err)
+			return 1
+		}
+	} else {
+		cfg, _, err := config.Load("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "void: failed to load config: %v\n", err)
+			return 1
+		}
+		merged, err := theme.ApplyPreset(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "void: theme: %v\n", err)
+			return 1
+		}
+		t = theme.Theme{Name: cfg.Preset, Symbol: merged.Prompt.Symbol, Segments: merged.Prompt.Segments, Palette: merged.Palette}
+	}
+
+	data := t.Encode()
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "void: theme: %v\n", err)
+		return 1
+	}
+	return 0
+}
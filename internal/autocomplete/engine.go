@@ -4,43 +4,243 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
 	"strings"
+	"time"
 )
 
+const maxSuggestions = 20
+
+// Kind classifies where a suggestion came from.
+type Kind int
+
+const (
+	KindCommand Kind = iota
+	KindBuiltin
+	KindHistory
+	KindSubcommand
+	KindFlag
+	KindValue
+	KindDirectory
+	KindFile
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindBuiltin:
+		return "builtin"
+	case KindHistory:
+		return "history"
+	case KindSubcommand:
+		return "subcommand"
+	case KindFlag:
+		return "flag"
+	case KindValue:
+		return "value"
+	case KindDirectory:
+		return "directory"
+	case KindFile:
+		return "file"
+	default:
+		return "command"
+	}
+}
+
+// Suggestion is a single completion candidate.
+type Suggestion struct {
+	Candidate   string
+	Description string
+	Kind        Kind
+}
+
+// Result holds the suggestions for the word under the cursor. Start and End
+// are rune offsets of the text a chosen candidate replaces.
+type Result struct {
+	Suggestions []Suggestion
+	Start       int
+	End         int
+}
+
 type Engine struct {
-	builtins []string
+	// ProviderTimeout bounds each dynamic provider call.
+	ProviderTimeout time.Duration
+
+	builtins  map[string]string
+	tools     map[string]*Spec
+	providers map[string]Provider
 }
 
 func New() *Engine {
-	return &Engine{builtins: []string{"cd", "dir", "copy", "del", "exit", "git", "go", "npm", "docker", "python"}}
+	e := &Engine{
+		builtins: map[string]string{
+			"cd":     "change directory",
+			"dir":    "list directory contents",
+			"copy":   "copy files",
+			"del":    "delete files",
+			"exit":   "leave void",
+			"git":    "version control",
+			"go":     "Go toolchain",
+			"npm":    "Node package manager",
+			"docker": "container runtime",
+			"python": "Python interpreter",
+		},
+		tools:           map[string]*Spec{},
+		providers:       builtinProviders(),
+		ProviderTimeout: defaultProviderTimeout,
+	}
+	specs, _ := loadEmbeddedSpecs()
+	e.AddSpecs(specs...)
+	return e
+}
+
+// AddSpecs registers completion specs. A spec for a tool that is already
+// known is merged into the existing tree.
+func (e *Engine) AddSpecs(specs ...Spec) {
+	for _, spec := range specs {
+		name := strings.ToLower(spec.Name)
+		if existing, ok := e.tools[name]; ok {
+			existing.merge(spec)
+			continue
+		}
+		e.tools[name] = &spec
+	}
 }
 
-func (e *Engine) Complete(prefix string, history []string) []string {
-	all := map[string]struct{}{}
-	for _, b := range e.builtins {
-		all[b] = struct{}{}
+// Complete returns suggestions for line with the cursor at rune offset
+// cursor. The first word completes to commands; later words complete to
+// directories after cd, to subcommands, flags, flag values and provider
+// results of known tools, and to file paths otherwise. Suggestions are ranked
+// by how often and how recently history used them in the same position.
+func (e *Engine) Complete(line string, cursor int, history []Usage) Result {
+	runes := []rune(line)
+	if cursor < 0 {
+		cursor = 0
 	}
-	for _, h := range history {
-		all[strings.Fields(h)[0]] = struct{}{}
+	if cursor > len(runes) {
+		cursor = len(runes)
 	}
-	for _, c := range fromPath() {
-		all[c] = struct{}{}
+	words := splitWords(runes, cursor)
+	current := words[len(words)-1]
+
+	var suggestions []Suggestion
+	if len(words) == 1 {
+		suggestions = e.completeCommand(current.text, history)
+	} else {
+		suggestions = e.completeArgument(words[0].text, words[1:len(words)-1], current)
 	}
 
-	matches := make([]string, 0)
-	for c := range all {
-		if strings.HasPrefix(strings.ToLower(c), strings.ToLower(prefix)) {
-			matches = append(matches, c)
+	preceding := make([]string, 0, len(words)-1)
+	for _, w := range words[:len(words)-1] {
+		preceding = append(preceding, w.text)
+	}
+	rank(suggestions, preceding, history)
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return Result{Suggestions: suggestions, Start: current.start, End: cursor}
+}
+
+func (e *Engine) completeCommand(prefix string, history []Usage) []Suggestion {
+	all := map[string]Suggestion{}
+	for _, c := range fromPath() {
+		all[c] = Suggestion{Candidate: c, Description: "command", Kind: KindCommand}
+	}
+	for _, h := range history {
+		fields := strings.Fields(h.Command)
+		if len(fields) == 0 {
+			continue
+		}
+		if _, ok := all[fields[0]]; !ok {
+			all[fields[0]] = Suggestion{Candidate: fields[0], Description: "history", Kind: KindHistory}
 		}
 	}
-	sort.Strings(matches)
-	if len(matches) > 20 {
-		matches = matches[:20]
+	for b, desc := range e.builtins {
+		all[b] = Suggestion{Candidate: b, Description: desc, Kind: KindBuiltin}
+	}
+
+	matches := make([]Suggestion, 0)
+	for c, s := range all {
+		if !hasPrefixFold(c, prefix) {
+			continue
+		}
+		if spec, ok := e.tools[strings.ToLower(c)]; ok && s.Kind != KindBuiltin && spec.Description != "" {
+			s.Description = spec.Description
+		}
+		matches = append(matches, s)
 	}
 	return matches
 }
 
+func (e *Engine) completeArgument(command string, args []word, current word) []Suggestion {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(command), filepath.Ext(command)))
+	switch name {
+	case "cd", "chdir", "pushd":
+		return completePaths(current, true)
+	}
+
+	spec, ok := e.tools[name]
+	if !ok {
+		return completePaths(current, false)
+	}
+
+	node := spec
+	var pending *Flag
+	for _, a := range args {
+		if f := node.flag(a.text); f != nil && f.takesValue() {
+			pending = f
+			continue
+		}
+		pending = nil
+		if sub := node.subcommand(a.text); sub != nil {
+			node = sub
+		}
+	}
+
+	matches := make([]Suggestion, 0)
+	switch {
+	case pending != nil && pending.Arg != "":
+		// A path argument, not a subcommand or provider result.
+		return completePaths(current, pending.Arg == "dir")
+	case pending != nil:
+		for _, v := range pending.Values {
+			if hasPrefixFold(v, current.text) {
+				matches = append(matches, Suggestion{Candidate: v, Description: pending.Name, Kind: KindValue})
+			}
+		}
+		return matches
+	case strings.HasPrefix(current.text, "-"):
+		for _, f := range node.Flags {
+			if hasPrefixFold(f.Name, current.text) {
+				matches = append(matches, Suggestion{Candidate: f.Name, Description: f.Description, Kind: KindFlag})
+			}
+		}
+		return matches
+	}
+
+	for _, sub := range node.Subcommands {
+		if hasPrefixFold(sub.Name, current.text) {
+			matches = append(matches, Suggestion{Candidate: sub.Name, Description: sub.Description, Kind: KindSubcommand})
+		}
+	}
+	if node.Provider != "" {
+		dir, _ := os.Getwd()
+		texts := make([]string, 0, len(args))
+		for _, a := range args {
+			texts = append(texts, a.text)
+		}
+		req := Request{Tool: name, Args: texts, Prefix: current.text, Dir: dir}
+		for _, s := range e.suggest(node.Provider, req) {
+			if hasPrefixFold(s.Candidate, current.text) {
+				s.Candidate = quoteIfNeeded(s.Candidate, current.quoted)
+				matches = append(matches, s)
+			}
+		}
+	}
+	if len(matches) > 0 || len(node.Subcommands) > 0 {
+		return matches
+	}
+	return completePaths(current, false)
+}
+
 func fromPath() []string {
 	path := os.Getenv("PATH")
 	if path == "" {
@@ -0,0 +1,182 @@
+package autocomplete
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func candidates(res Result) []string {
+	out := make([]string, 0, len(res.Suggestions))
+	for _, s := range res.Suggestions {
+		out = append(out, s.Candidate)
+	}
+	return out
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	orig, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(orig) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+}
+
+func TestSplitWordsHandlesQuotesAndTrailingSpace(t *testing.T) {
+	words := splitWords([]rune(`cat "my fi`), 10)
+	if len(words) != 2 {
+		t.Fatalf("expected 2 words, got %#v", words)
+	}
+	if words[1].text != "my fi" || words[1].start != 4 || !words[1].quoted {
+		t.Fatalf("unexpected quoted word: %#v", words[1])
+	}
+
+	words = splitWords([]rune("git "), 4)
+	if len(words) != 2 || words[1].text != "" || words[1].start != 4 {
+		t.Fatalf("expected empty current word after space, got %#v", words)
+	}
+}
+
+func TestCompleteFirstWordUsesBuiltinsAndHistory(t *testing.T) {
+	t.Setenv("PATH", "")
+	e := New()
+	res := e.Complete("do", 2, []Usage{{Command: "dotnet build"}, {Command: "ls"}})
+	got := candidates(res)
+	if len(got) != 2 || got[0] != "docker" || got[1] != "dotnet" {
+		t.Fatalf("unexpected candidates: %#v", got)
+	}
+	if res.Suggestions[0].Kind != KindBuiltin || res.Suggestions[1].Kind != KindHistory {
+		t.Fatalf("unexpected kinds: %#v", res.Suggestions)
+	}
+	if res.Start != 0 || res.End != 2 {
+		t.Fatalf("unexpected replace range %d..%d", res.Start, res.End)
+	}
+}
+
+func TestCompleteCdOffersOnlyDirectories(t *testing.T) {
+	tmp := t.TempDir()
+	for _, dir := range []string{"src", "scripts", ".secret"} {
+		if err := os.Mkdir(filepath.Join(tmp, dir), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "setup.py"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	chdir(t, tmp)
+
+	res := New().Complete("cd s", 4, nil)
+	got := candidates(res)
+	sep := string(filepath.Separator)
+	if len(got) != 2 || got[0] != "scripts"+sep || got[1] != "src"+sep {
+		t.Fatalf("expected only directories, got %#v", got)
+	}
+	if res.Suggestions[0].Kind != KindDirectory || res.Start != 3 {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+
+	if got := candidates(New().Complete("cd .s", 5, nil)); len(got) != 1 || got[0] != ".secret"+sep {
+		t.Fatalf("expected hidden directory when prefix starts with a dot, got %#v", got)
+	}
+}
+
+func TestCompleteFilesQuotesSpaces(t *testing.T) {
+	tmp := t.TempDir()
+	if err := os.Mkdir(filepath.Join(tmp, "docs"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "docs", "release notes.md"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	chdir(t, tmp)
+
+	res := New().Complete("cat docs/re", 11, nil)
+	got := candidates(res)
+	if len(got) != 1 || got[0] != `"docs/release notes.md"` {
+		t.Fatalf("expected quoted path, got %#v", got)
+	}
+	if res.Suggestions[0].Kind != KindFile || res.Start != 4 || res.End != 11 {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+
+	res = New().Complete(`cat "docs/release no`, 20, nil)
+	if got := candidates(res); len(got) != 1 || got[0] != `"docs/release notes.md"` || res.Start != 4 {
+		t.Fatalf("expected completion inside open quote, got %#v", res)
+	}
+}
+
+func TestCompleteIntoQuotedDirectory(t *testing.T) {
+	tmp := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmp, "my dir", "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "my dir", "a.txt"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	chdir(t, tmp)
+
+	// The directory is offered with the quote left open, so accepting it
+	// adds no space and the next Tab continues inside it.
+	sep := string(filepath.Separator)
+	got := candidates(New().Complete("cat my", 6, nil))
+	if len(got) != 1 || got[0] != `"my dir`+sep {
+		t.Fatalf("expected an open-quoted directory, got %#v", got)
+	}
+	line := "cat " + got[0]
+	res := New().Complete(line, len([]rune(line)), nil)
+	got = candidates(res)
+	if len(got) != 2 || got[0] != `"my dir`+sep+`a.txt"` || got[1] != `"my dir`+sep+`sub`+sep || res.Start != 4 {
+		t.Fatalf("expected the entries of the quoted directory, got %#v", res)
+	}
+}
+
+func TestCompleteToolSubcommandsAndFlags(t *testing.T) {
+	e := New()
+
+	if got := candidates(e.Complete("git ch", 6, nil)); len(got) != 1 || got[0] != "checkout" {
+		t.Fatalf("expected git subcommand, got %#v", got)
+	}
+	if got := candidates(e.Complete("go mod t", 8, nil)); len(got) != 1 || got[0] != "tidy" {
+		t.Fatalf("expected nested subcommand, got %#v", got)
+	}
+	res := e.Complete("git commit --a", 14, nil)
+	if got := candidates(res); len(got) != 1 || got[0] != "--amend" || res.Suggestions[0].Kind != KindFlag {
+		t.Fatalf("expected commit flag, got %#v", res)
+	}
+
+	e.tools["deploy"] = &Spec{Name: "deploy", Flags: []Flag{{Name: "--env", Values: []string{"staging", "production"}}}}
+	res = e.Complete("deploy --env p", 14, nil)
+	if got := candidates(res); len(got) != 1 || got[0] != "production" || res.Suggestions[0].Kind != KindValue {
+		t.Fatalf("expected flag value, got %#v", res)
+	}
+}
+
+func TestCompleteRanksByFrecency(t *testing.T) {
+	t.Setenv("PATH", "")
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	orig := now
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = orig })
+
+	e := New()
+	history := []Usage{
+		{Command: "git status", Count: 3, Last: fixed.Add(-30 * 24 * time.Hour)},
+		{Command: "git stash pop", Count: 2, Last: fixed.Add(-10 * time.Minute)},
+		{Command: "go test ./...", Count: 50, Last: fixed.Add(-time.Hour / 2)},
+	}
+
+	got := candidates(e.Complete("git st", 6, history))
+	if len(got) != 2 || got[0] != "stash" || got[1] != "status" {
+		t.Fatalf("expected recent stash before old status, got %#v", got)
+	}
+
+	res := e.Complete("g", 1, history)
+	if got := candidates(res); len(got) < 2 || got[0] != "go" || got[1] != "git" {
+		t.Fatalf("expected go ranked first, got %#v", got)
+	}
+}
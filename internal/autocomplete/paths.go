@@ -0,0 +1,109 @@
+package autocomplete
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// completePaths lists entries of the directory named by the word's prefix.
+// Hidden entries are offered only when the typed name starts with a dot.
+func completePaths(w word, dirsOnly bool) []Suggestion {
+	dirPart, base := splitPathPrefix(w.text)
+	searchDir := dirPart
+	if searchDir == "" {
+		searchDir = "."
+	}
+	entries, err := os.ReadDir(expandHome(searchDir))
+	if err != nil {
+		return nil
+	}
+
+	sep := pathSeparator(dirPart)
+	out := make([]Suggestion, 0, len(entries))
+	for _, ent := range entries {
+		name := ent.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if !hasPrefixFold(name, base) {
+			continue
+		}
+		isDir := ent.IsDir()
+		if ent.Type()&os.ModeSymlink != 0 {
+			if fi, err := os.Stat(filepath.Join(expandHome(searchDir), name)); err == nil {
+				isDir = fi.IsDir()
+			}
+		}
+		if dirsOnly && !isDir {
+			continue
+		}
+
+		s := Suggestion{Candidate: quoteIfNeeded(dirPart+name, w.quoted), Description: "file", Kind: KindFile}
+		if isDir {
+			// The quote stays open so the word goes on into the directory.
+			s.Candidate = openQuoteIfNeeded(dirPart+name+sep, w.quoted)
+			s.Description = "directory"
+			s.Kind = KindDirectory
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// splitPathPrefix splits a typed path into its directory part, including the
+// trailing separator, and the partial entry name.
+func splitPathPrefix(typed string) (string, string) {
+	idx := strings.LastIndex(typed, "/")
+	if runtime.GOOS == "windows" {
+		if b := strings.LastIndex(typed, `\`); b > idx {
+			idx = b
+		}
+	}
+	if idx == -1 {
+		return "", typed
+	}
+	return typed[:idx+1], typed[idx+1:]
+}
+
+func pathSeparator(dirPart string) string {
+	switch {
+	case strings.HasSuffix(dirPart, `\`):
+		return `\`
+	case strings.HasSuffix(dirPart, "/"):
+		return "/"
+	}
+	return string(filepath.Separator)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+func quoteIfNeeded(value string, force bool) string {
+	if force || strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// openQuoteIfNeeded is quoteIfNeeded without the closing quote, for a word
+// that is not finished yet.
+func openQuoteIfNeeded(value string, force bool) string {
+	if force || strings.ContainsAny(value, " \t") {
+		return `"` + value
+	}
+	return value
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
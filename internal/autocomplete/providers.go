@@ -0,0 +1,268 @@
+package autocomplete
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultProviderTimeout = 300 * time.Millisecond
+
+// Request describes the argument a provider is asked to complete.
+type Request struct {
+	Tool   string
+	Args   []string
+	Prefix string
+	Dir    string
+}
+
+// Provider is a dynamic completion source referenced by a spec's provider
+// key. Suggest should honour ctx; the engine stops waiting once it expires.
+type Provider interface {
+	Suggest(ctx context.Context, req Request) ([]Suggestion, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(ctx context.Context, req Request) ([]Suggestion, error)
+
+func (f ProviderFunc) Suggest(ctx context.Context, req Request) ([]Suggestion, error) {
+	return f(ctx, req)
+}
+
+// RegisterProvider makes p available to specs under name, replacing any
+// provider previously registered with that name.
+func (e *Engine) RegisterProvider(name string, p Provider) {
+	e.providers[name] = p
+}
+
+func builtinProviders() map[string]Provider {
+	return map[string]Provider{
+		"git-refs":     ProviderFunc(gitRefs),
+		"npm-scripts":  ProviderFunc(npmScripts),
+		"make-targets": ProviderFunc(makeTargets),
+		"go-packages":  ProviderFunc(goPackages),
+	}
+}
+
+// suggest runs the named provider with the engine's timeout. A provider
+// that fails or does not answer in time contributes nothing.
+func (e *Engine) suggest(name string, req Request) []Suggestion {
+	p, ok := e.providers[name]
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.ProviderTimeout)
+	defer cancel()
+
+	done := make(chan []Suggestion, 1)
+	go func() {
+		suggestions, err := p.Suggest(ctx, req)
+		if err != nil {
+			suggestions = nil
+		}
+		done <- suggestions
+	}()
+	select {
+	case suggestions := <-done:
+		return suggestions
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func gitRefs(ctx context.Context, req Request) ([]Suggestion, error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref", "--format=%(refname)", "refs/heads", "refs/remotes", "refs/tags")
+	cmd.Dir = req.Dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var suggestions []Suggestion
+	for _, ref := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		var name, desc string
+		switch {
+		case strings.HasPrefix(ref, "refs/heads/"):
+			name, desc = strings.TrimPrefix(ref, "refs/heads/"), "branch"
+		case strings.HasPrefix(ref, "refs/remotes/"):
+			name, desc = strings.TrimPrefix(ref, "refs/remotes/"), "remote branch"
+			if strings.HasSuffix(name, "/HEAD") {
+				continue
+			}
+		case strings.HasPrefix(ref, "refs/tags/"):
+			name, desc = strings.TrimPrefix(ref, "refs/tags/"), "tag"
+		default:
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{Candidate: name, Description: desc, Kind: KindValue})
+	}
+	return suggestions, nil
+}
+
+func npmScripts(_ context.Context, req Request) ([]Suggestion, error) {
+	path, ok := findUp(req.Dir, "package.json")
+	if !ok {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, err
+	}
+	suggestions := make([]Suggestion, 0, len(pkg.Scripts))
+	for name, script := range pkg.Scripts {
+		suggestions = append(suggestions, Suggestion{Candidate: name, Description: script, Kind: KindValue})
+	}
+	return suggestions, nil
+}
+
+// makeTargets lists explicit targets of the makefile make would read,
+// honouring -C and -f. Pattern rules and special targets are skipped.
+func makeTargets(_ context.Context, req Request) ([]Suggestion, error) {
+	dir, file := req.Dir, ""
+	for i := 0; i+1 < len(req.Args); i++ {
+		switch req.Args[i] {
+		case "-C":
+			dir = resolveDir(req.Dir, req.Args[i+1])
+		case "-f":
+			file = req.Args[i+1]
+		}
+	}
+	var data []byte
+	var err error
+	if file != "" {
+		data, err = os.ReadFile(resolveDir(dir, file))
+	} else {
+		for _, name := range []string{"GNUmakefile", "makefile", "Makefile"} {
+			if data, err = os.ReadFile(filepath.Join(dir, name)); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var suggestions []Suggestion
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '\t' || line[0] == ' ' || line[0] == '#' {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx <= 0 || strings.HasPrefix(line[idx:], ":=") || strings.ContainsAny(line[:idx], "=$%") {
+			continue
+		}
+		for _, target := range strings.Fields(line[:idx]) {
+			if strings.HasPrefix(target, ".") || seen[target] {
+				continue
+			}
+			seen[target] = true
+			suggestions = append(suggestions, Suggestion{Candidate: target, Description: "target", Kind: KindValue})
+		}
+	}
+	return suggestions, scanner.Err()
+}
+
+// goPackages lists the packages of the enclosing module as paths relative to
+// the working directory, plus "./..." for the whole tree.
+func goPackages(ctx context.Context, req Request) ([]Suggestion, error) {
+	modFile, ok := findUp(req.Dir, "go.mod")
+	if !ok {
+		return nil, nil
+	}
+	data, err := os.ReadFile(modFile)
+	if err != nil {
+		return nil, err
+	}
+	module := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+			module = strings.Trim(fields[1], `"`)
+			break
+		}
+	}
+	root := filepath.Dir(modFile)
+
+	suggestions := []Suggestion{{Candidate: "./...", Description: "all packages", Kind: KindValue}}
+	seen := map[string]bool{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			if path != root {
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".go") {
+			return nil
+		}
+		pkgDir := filepath.Dir(path)
+		if seen[pkgDir] {
+			return nil
+		}
+		seen[pkgDir] = true
+		rel, err := filepath.Rel(req.Dir, pkgDir)
+		if err != nil {
+			return nil
+		}
+		candidate := filepath.ToSlash(rel)
+		if !strings.HasPrefix(candidate, ".") {
+			candidate = "./" + candidate
+		}
+		importPath := module
+		if fromRoot, err := filepath.Rel(root, pkgDir); err == nil && fromRoot != "." {
+			importPath += "/" + filepath.ToSlash(fromRoot)
+		}
+		suggestions = append(suggestions, Suggestion{Candidate: candidate, Description: importPath, Kind: KindValue})
+		return nil
+	})
+	return suggestions, err
+}
+
+// findUp looks for name in dir and its parents.
+func findUp(dir, name string) (string, bool) {
+	for {
+		path := filepath.Join(dir, name)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+func resolveDir(base, path string) string {
+	path = expandHome(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
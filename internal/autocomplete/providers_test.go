@@ -0,0 +1,160 @@
+package autocomplete
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestNpmRunCompletesScriptsFromNearestPackageJSON(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "package.json"), `{"scripts": {"build": "tsc", "bench": "node bench.js", "lint": "eslint ."}}`)
+	if err := os.Mkdir(filepath.Join(tmp, "src"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	chdir(t, filepath.Join(tmp, "src"))
+
+	res := New().Complete("npm run b", 9, nil)
+	got := candidates(res)
+	if len(got) != 2 || got[0] != "bench" || got[1] != "build" {
+		t.Fatalf("expected scripts, got %#v", got)
+	}
+	if res.Suggestions[1].Description != "tsc" || res.Start != 8 {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+}
+
+func TestMakeCompletesTargets(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "Makefile"), "CC := gcc\n.PHONY: all test\nall: build\n\ttouch x\nbuild test: deps\n%.o: %.c\nVERSION = 1:2\n")
+	chdir(t, tmp)
+
+	got := candidates(New().Complete("make ", 5, nil))
+	want := []string{"all", "build", "test"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %#v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %#v", want, got)
+		}
+	}
+
+	writeFile(t, filepath.Join(tmp, "sub", "Makefile"), "install:\n")
+	if got := candidates(New().Complete("make -C sub i", 13, nil)); len(got) != 1 || got[0] != "install" {
+		t.Fatalf("expected target from -C directory, got %#v", got)
+	}
+
+	// The values of -C and -f are paths, not targets.
+	sep := string(filepath.Separator)
+	if got := candidates(New().Complete("make -C ", 8, nil)); len(got) != 1 || got[0] != "sub"+sep {
+		t.Fatalf("expected directories after -C, got %#v", got)
+	}
+	if got := candidates(New().Complete("make -f M", 9, nil)); len(got) != 1 || got[0] != "Makefile" {
+		t.Fatalf("expected files after -f, got %#v", got)
+	}
+}
+
+func TestGoTestCompletesModulePackages(t *testing.T) {
+	tmp := t.TempDir()
+	writeFile(t, filepath.Join(tmp, "go.mod"), "module example.com/app\n\ngo 1.22\n")
+	writeFile(t, filepath.Join(tmp, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(tmp, "internal", "a.go"), "package internal\n")
+	writeFile(t, filepath.Join(tmp, "internal", "store", "store.go"), "package store\n")
+	writeFile(t, filepath.Join(tmp, "internal", "testdata", "x.go"), "package x\n")
+	writeFile(t, filepath.Join(tmp, "tools", "go.mod"), "module example.com/tools\n")
+	writeFile(t, filepath.Join(tmp, "tools", "gen.go"), "package tools\n")
+	chdir(t, tmp)
+
+	res := New().Complete("go test ./in", 12, nil)
+	got := candidates(res)
+	if len(got) != 2 || got[0] != "./internal" || got[1] != "./internal/store" {
+		t.Fatalf("expected module packages, got %#v", got)
+	}
+	if res.Suggestions[1].Description != "example.com/app/internal/store" {
+		t.Fatalf("unexpected description: %#v", res.Suggestions[1])
+	}
+	if got := candidates(New().Complete("go test ./.", 11, nil)); len(got) != 1 || got[0] != "./..." {
+		t.Fatalf("expected ./..., got %#v", got)
+	}
+}
+
+func TestGitCheckoutCompletesRefs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmp := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmp
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("commit", "-q", "--allow-empty", "-m", "init")
+	run("branch", "feature/login")
+	run("tag", "v1.0.0")
+	run("update-ref", "refs/remotes/origin/fix", "HEAD")
+	chdir(t, tmp)
+
+	e := New()
+	e.ProviderTimeout = 5 * time.Second
+	res := e.Complete("git checkout ", 13, nil)
+	got := map[string]string{}
+	for _, s := range res.Suggestions {
+		got[s.Candidate] = s.Description
+	}
+	want := map[string]string{"main": "branch", "feature/login": "branch", "origin/fix": "remote branch", "v1.0.0": "tag"}
+	for name, desc := range want {
+		if got[name] != desc {
+			t.Fatalf("expected %s (%s), got %#v", name, desc, got)
+		}
+	}
+}
+
+func TestSlowProviderTimesOut(t *testing.T) {
+	e := New()
+	e.ProviderTimeout = 20 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	e.RegisterProvider("slow", ProviderFunc(func(ctx context.Context, req Request) ([]Suggestion, error) {
+		<-release
+		return []Suggestion{{Candidate: "late"}}, nil
+	}))
+	e.RegisterProvider("fast", ProviderFunc(func(ctx context.Context, req Request) ([]Suggestion, error) {
+		if req.Tool != "deploy" || len(req.Args) != 1 || req.Args[0] != "release" || req.Prefix != "s" {
+			t.Errorf("unexpected request: %#v", req)
+		}
+		return []Suggestion{{Candidate: "staging", Kind: KindValue}, {Candidate: "prod", Kind: KindValue}}, nil
+	}))
+	e.AddSpecs(Spec{Name: "deploy", Provider: "slow", Subcommands: []Spec{{Name: "release", Provider: "fast"}}})
+
+	if got := candidates(e.Complete("deploy release s", 16, nil)); len(got) != 1 || got[0] != "staging" {
+		t.Fatalf("expected provider suggestion, got %#v", got)
+	}
+
+	start := time.Now()
+	res := e.Complete("deploy x", 8, nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("slow provider blocked completion for %v", elapsed)
+	}
+	if len(res.Suggestions) != 0 {
+		t.Fatalf("expected no suggestions from timed out provider, got %#v", res.Suggestions)
+	}
+}
@@ -0,0 +1,70 @@
+package autocomplete
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+var now = time.Now
+
+// Usage is a previously run command with its use count and last use.
+type Usage struct {
+	Command string
+	Count   int
+	Last    time.Time
+}
+
+// frecency weights a use count by how recently the command last ran.
+func frecency(u Usage, at time.Time) float64 {
+	weight := 0.25
+	switch age := at.Sub(u.Last); {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 1
+	case age < 30*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(u.Count) * weight
+}
+
+// rank orders suggestions by the frecency of the commands in history that
+// used the candidate after the same preceding words, then alphabetically.
+func rank(suggestions []Suggestion, preceding []string, history []Usage) {
+	scores := map[string]float64{}
+	at := now()
+	for _, u := range history {
+		runes := []rune(u.Command)
+		words := splitWords(runes, len(runes))
+		if len(words) <= len(preceding) || !sameWords(words[:len(preceding)], preceding) {
+			continue
+		}
+		scores[rankKey(words[len(preceding)].text)] += frecency(u, at)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		si, sj := scores[rankKey(suggestions[i].Candidate)], scores[rankKey(suggestions[j].Candidate)]
+		if si != sj {
+			return si > sj
+		}
+		return strings.ToLower(suggestions[i].Candidate) < strings.ToLower(suggestions[j].Candidate)
+	})
+}
+
+func sameWords(words []word, texts []string) bool {
+	for i, w := range words {
+		if w.text != texts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// rankKey normalises a candidate or history word so "src", "src/" and
+// "\"src\"" count as the same argument.
+func rankKey(s string) string {
+	return strings.TrimRight(strings.Trim(s, `"`), `/\`)
+}
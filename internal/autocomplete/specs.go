@@ -0,0 +1,260 @@
+package autocomplete
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed specs/*.yaml
+var specFS embed.FS
+
+// Spec is the completion tree for a tool or one of its subcommands. Provider
+// names a registered dynamic source for the node's positional arguments.
+type Spec struct {
+	Name        string
+	Description string
+	Provider    string
+	Subcommands []Spec
+	Flags       []Flag
+}
+
+// Flag is a command-line option with optional fixed values.
+type Flag struct {
+	Name        string
+	Description string
+	Values      []string
+	// Arg is "file" or "dir" when the flag takes a path as its value.
+	Arg string
+}
+
+// takesValue reports whether the word after f is f's value.
+func (f *Flag) takesValue() bool {
+	return len(f.Values) > 0 || f.Arg != ""
+}
+
+func (s *Spec) subcommand(name string) *Spec {
+	for i := range s.Subcommands {
+		if s.Subcommands[i].Name == name {
+			return &s.Subcommands[i]
+		}
+	}
+	return nil
+}
+
+func (s *Spec) flag(name string) *Flag {
+	for i := range s.Flags {
+		if s.Flags[i].Name == name {
+			return &s.Flags[i]
+		}
+	}
+	return nil
+}
+
+// merge overlays other onto s: matching subcommands are merged recursively,
+// matching flags are replaced and new entries are appended.
+func (s *Spec) merge(other Spec) {
+	if other.Description != "" {
+		s.Description = other.Description
+	}
+	if other.Provider != "" {
+		s.Provider = other.Provider
+	}
+	for _, f := range other.Flags {
+		if existing := s.flag(f.Name); existing != nil {
+			*existing = f
+			continue
+		}
+		s.Flags = append(s.Flags, f)
+	}
+	for _, sub := range other.Subcommands {
+		if existing := s.subcommand(sub.Name); existing != nil {
+			existing.merge(sub)
+			continue
+		}
+		s.Subcommands = append(s.Subcommands, sub)
+	}
+}
+
+// LoadSpecDir parses every *.yaml and *.yml file in dir. A missing directory
+// yields no specs. Valid files are returned even when others fail; the error
+// then lists each malformed file.
+func LoadSpecDir(dir string) ([]Spec, error) {
+	specs, err := loadSpecFS(os.DirFS(dir), ".")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return specs, err
+}
+
+func loadEmbeddedSpecs() ([]Spec, error) {
+	return loadSpecFS(specFS, "specs")
+}
+
+func loadSpecFS(fsys fs.FS, dir string) ([]Spec, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var specs []Spec
+	var errs []error
+	for _, ent := range entries {
+		ext := strings.ToLower(filepath.Ext(ent.Name()))
+		if ent.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, ent.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		spec, err := ParseSpec(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ent.Name(), err))
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	return specs, errors.Join(errs...)
+}
+
+// ParseSpec decodes a YAML completion spec:
+//
+//	tool: mytool
+//	description: My tool
+//	subcommands:
+//	  - name: deploy
+//	    description: Deploy the app
+//	    provider: git-refs
+//	    flags:
+//	      - name: --env
+//	        values: [staging, production]
+//	      - name: --config
+//	        arg: file
+func ParseSpec(data []byte) (Spec, error) {
+	var file specFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Spec{}, err
+	}
+	if strings.TrimSpace(file.Tool) == "" {
+		return Spec{}, errors.New("missing required key \"tool\"")
+	}
+	spec := Spec{Name: file.Tool, Description: file.Description, Provider: file.Provider}
+	spec.Flags = convertFlags(file.Flags)
+	spec.Subcommands = convertNodes(file.Subcommands)
+	return spec, nil
+}
+
+type specFile struct {
+	Tool        string     `yaml:"tool"`
+	Description string     `yaml:"description"`
+	Provider    string     `yaml:"provider"`
+	Subcommands []specNode `yaml:"subcommands"`
+	Flags       []flagNode `yaml:"flags"`
+}
+
+type specNode struct {
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description"`
+	Provider    string     `yaml:"provider"`
+	Subcommands []specNode `yaml:"subcommands"`
+	Flags       []flagNode `yaml:"flags"`
+}
+
+type flagNode struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Values      []string `yaml:"values"`
+	Arg         string   `yaml:"arg"`
+}
+
+func (f *specFile) UnmarshalYAML(node *yaml.Node) error {
+	if err := checkKeys(node, "tool", "description", "provider", "subcommands", "flags"); err != nil {
+		return err
+	}
+	type plain specFile
+	return node.Decode((*plain)(f))
+}
+
+func (n *specNode) UnmarshalYAML(node *yaml.Node) error {
+	if err := checkKeys(node, "name", "description", "provider", "subcommands", "flags"); err != nil {
+		return err
+	}
+	type plain specNode
+	if err := node.Decode((*plain)(n)); err != nil {
+		return err
+	}
+	if strings.TrimSpace(n.Name) == "" {
+		return fmt.Errorf("line %d: subcommand is missing \"name\"", node.Line)
+	}
+	return nil
+}
+
+func (f *flagNode) UnmarshalYAML(node *yaml.Node) error {
+	if err := checkKeys(node, "name", "description", "values", "arg"); err != nil {
+		return err
+	}
+	type plain flagNode
+	if err := node.Decode((*plain)(f)); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(f.Name, "-") {
+		return fmt.Errorf("line %d: flag name %q must start with \"-\"", node.Line, f.Name)
+	}
+	if f.Arg != "" && f.Arg != "file" && f.Arg != "dir" {
+		return fmt.Errorf("line %d: flag %s: arg must be \"file\" or \"dir\", not %q", node.Line, f.Name, f.Arg)
+	}
+	return nil
+}
+
+func checkKeys(node *yaml.Node, allowed ...string) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		known := false
+		for _, a := range allowed {
+			if key.Value == a {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("line %d: unknown key %q (expected one of: %s)", key.Line, key.Value, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+func convertNodes(nodes []specNode) []Spec {
+	out := make([]Spec, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, Spec{
+			Name:        n.Name,
+			Description: n.Description,
+			Provider:    n.Provider,
+			Subcommands: convertNodes(n.Subcommands),
+			Flags:       convertFlags(n.Flags),
+		})
+	}
+	return out
+}
+
+func convertFlags(flags []flagNode) []Flag {
+	out := make([]Flag, 0, len(flags))
+	for _, f := range flags {
+		out = append(out, Flag{Name: f.Name, Description: f.Description, Values: f.Values, Arg: f.Arg})
+	}
+	return out
+}
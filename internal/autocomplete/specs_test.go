@@ -0,0 +1,97 @@
+package autocomplete
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedSpecsParse(t *testing.T) {
+	specs, err := loadEmbeddedSpecs()
+	if err != nil {
+		t.Fatalf("embedded specs: %v", err)
+	}
+	names := map[string]bool{}
+	for _, s := range specs {
+		names[s.Name] = true
+	}
+	for _, tool := range []string{"git", "go", "npm", "docker", "pip", "cargo"} {
+		if !names[tool] {
+			t.Fatalf("expected embedded spec for %s, got %#v", tool, names)
+		}
+	}
+}
+
+func TestParseSpecReportsMalformedSpecs(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{name: "missing tool", yaml: "subcommands: []\n", want: `missing required key "tool"`},
+		{name: "unknown key", yaml: "tool: x\nsubcommands:\n  - name: a\n    flag: []\n", want: `line 4: unknown key "flag"`},
+		{name: "unnamed subcommand", yaml: "tool: x\nsubcommands:\n  - description: a\n", want: `line 3: subcommand is missing "name"`},
+		{name: "bad flag", yaml: "tool: x\nflags:\n  - name: env\n", want: `line 3: flag name "env" must start with "-"`},
+		{name: "bad arg", yaml: "tool: x\nflags:\n  - name: -o\n    arg: path\n", want: `line 3: flag -o: arg must be "file" or "dir"`},
+		{name: "syntax", yaml: "tool: x\n  bad: [\n", want: "line"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseSpec([]byte(tc.yaml))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadSpecDirMergesWithEmbeddedSpecs(t *testing.T) {
+	dir := t.TempDir()
+	deploy := `tool: deploy
+description: internal deploy CLI
+subcommands:
+  - name: release
+    description: Deploy the app
+    flags:
+      - name: --env
+        description: Target environment
+        values: [staging, production]
+  - name: rollback
+    description: Rollback last deploy
+`
+	gitExtra := "tool: git\nsubcommands:\n  - name: worktree\n    description: manage worktrees\n"
+	broken := "tool: broken\nsubcommands:\n  - nme: x\n"
+	for name, content := range map[string]string{"deploy.yaml": deploy, "git.yml": gitExtra, "broken.yaml": broken, "notes.txt": "ignored"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	specs, err := LoadSpecDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "broken.yaml: line 3") {
+		t.Fatalf("expected malformed file to be reported with line, got %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected valid specs to load, got %d", len(specs))
+	}
+
+	e := New()
+	e.AddSpecs(specs...)
+	if got := candidates(e.Complete("deploy r", 8, nil)); len(got) != 2 || got[0] != "release" || got[1] != "rollback" {
+		t.Fatalf("expected deploy subcommands, got %#v", got)
+	}
+	if got := candidates(e.Complete("deploy release --env s", 23, nil)); len(got) != 1 || got[0] != "staging" {
+		t.Fatalf("expected deploy flag values, got %#v", got)
+	}
+	if got := candidates(e.Complete("git w", 5, nil)); len(got) != 1 || got[0] != "worktree" {
+		t.Fatalf("expected user git subcommand to merge, got %#v", got)
+	}
+	if got := candidates(e.Complete("git com", 7, nil)); len(got) != 1 || got[0] != "commit" {
+		t.Fatalf("expected embedded git subcommands to survive merge, got %#v", got)
+	}
+
+	if specs, err := LoadSpecDir(filepath.Join(dir, "missing")); err != nil || specs != nil {
+		t.Fatalf("expected missing dir to be ignored, got %v %v", specs, err)
+	}
+}
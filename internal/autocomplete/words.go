@@ -0,0 +1,65 @@
+package autocomplete
+
+import "unicode"
+
+// word is a shell word of the input line with quotes removed. start and end
+// are rune offsets of the raw text, quotes included.
+type word struct {
+	text   string
+	start  int
+	end    int
+	quoted bool
+}
+
+// splitWords tokenizes line up to cursor. The last word is the one being
+// completed; it is empty when the cursor follows whitespace.
+func splitWords(line []rune, cursor int) []word {
+	if cursor > len(line) {
+		cursor = len(line)
+	}
+	words := make([]word, 0, 4)
+	var current []rune
+	start := -1
+	quote := rune(0)
+	quoted := false
+
+	flush := func(end int) {
+		words = append(words, word{text: string(current), start: start, end: end, quoted: quoted})
+		current = current[:0]
+		start = -1
+		quoted = false
+	}
+
+	for i := 0; i < cursor; i++ {
+		r := line[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current = append(current, r)
+			}
+		case r == '"' || r == '\'':
+			if start == -1 {
+				start = i
+			}
+			quote = r
+			quoted = true
+		case unicode.IsSpace(r):
+			if start != -1 {
+				flush(i)
+			}
+		default:
+			if start == -1 {
+				start = i
+			}
+			current = append(current, r)
+		}
+	}
+	if start == -1 {
+		words = append(words, word{start: cursor, end: cursor})
+	} else {
+		flush(cursor)
+	}
+	return words
+}
@@ -1,12 +1,12 @@
 package config
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -18,6 +18,18 @@ type Config struct {
 	History HistoryConfig
 	Alias   map[string]string
 	API     APIConfig
+	// Segment holds the [segment.<name>] tables, keyed by segment name.
+	Segment map[string]SegmentConfig
+
+	// explicit holds the keys LoadLayers took from a file or the
+	// environment rather than the defaults.
+	explicit map[string]bool
+}
+
+// Explicit reports whether key was set by a config file or a VOID_*
+// variable when c was loaded with LoadLayers.
+func (c Config) Explicit(key string) bool {
+	return c.explicit[key]
 }
 
 type ShellConfig struct {
@@ -28,11 +40,52 @@ type ShellConfig struct {
 type PromptConfig struct {
 	Symbol   string
 	Segments []string
+	// RightSegments are drawn at the right edge of the badge line.
+	RightSegments []string
+	// Transient collapses each submitted prompt into a single line.
+	Transient  bool
+	ColorMode  string
+	PathColors string
+	// Timeout is how many milliseconds a slow segment may take before the
+	// prompt is drawn without it.
+	Timeout int
+	// GitIndicators are the git status details shown after the branch.
+	GitIndicators []string
+	// Notify is "off", "bell" or "desktop": how to signal that a command
+	// running at least NotifyAfter seconds has finished.
+	Notify      string
+	NotifyAfter int
+}
+
+// SegmentConfig styles one prompt segment. Empty fields keep the segment's
+// built-in behaviour.
+type SegmentConfig struct {
+	// Format is a template such as "{code} {errors}"; each segment documents
+	// its own placeholders.
+	Format string
+	// Icon replaces the segment's icon; "none" removes it.
+	Icon      string
+	FG        string
+	BG        string
+	MaxLength int
+	// When is "always", "never", "error", "success" or "env:NAME".
+	When string
+	// TimeFormat is the Go time layout used by the time segment.
+	TimeFormat string
+	// Timeout overrides prompt.timeout for this segment, in milliseconds.
+	Timeout int
+	// Threshold is how many milliseconds a command must run before the
+	// duration segment shows.
+	Threshold int
 }
 
 type HistoryConfig struct {
-	Path    string
-	MaxSize int
+	Path          string
+	MaxSize       int
+	IgnoreSpace   bool
+	Redact        []string
+	RedactMode    string
+	DetectSecrets bool
 }
 
 type APIConfig struct {
@@ -41,32 +94,82 @@ type APIConfig struct {
 
 func Default() Config {
 	return Config{
-		Preset:  "cyberpunk",
-		Shell:   ShellConfig{Executable: defaultShell(), Args: []string{}},
-		Prompt:  PromptConfig{Symbol: ">", Segments: []string{"user", "path", "time"}},
-		History: HistoryConfig{Path: "~/.void/history", MaxSize: 5000},
+		Preset: "cyberpunk",
+		Shell:  ShellConfig{Executable: defaultShell(), Args: []string{}},
+		Prompt: PromptConfig{
+			Symbol:        ">",
+			Segments:      []string{"user", "path", "time"},
+			RightSegments: []string{},
+			ColorMode:     "auto",
+			PathColors:    "gradient",
+			Timeout:       200,
+			GitIndicators: []string{"ahead", "behind", "staged", "modified", "untracked", "conflicted", "stash", "operation"},
+			Notify:        "off",
+			NotifyAfter:   10,
+		},
+		History: HistoryConfig{Path: "~/.void/history", MaxSize: 5000, IgnoreSpace: true, RedactMode: "mask", DetectSecrets: true},
 		Alias:   map[string]string{},
 		Palette: map[string]string{},
+		Segment: map[string]SegmentConfig{
+			"user":      {MaxLength: 6},
+			"time":      {TimeFormat: "3:04 PM"},
+			"exit_code": {Format: "{code} {errors}"},
+			"duration":  {Threshold: 2000},
+		},
 	}
 }
 
+// Load merges every configuration layer for the working directory and
+// returns the result with the path of the user config file, if any.
+// Skipped settings are reported on stderr.
 func Load(fromFlag string) (Config, string, error) {
-	cfg := Default()
-	path := resolveConfigPath(fromFlag)
-	if path == "" {
-		return cfg, "", nil
+	dir, _ := os.Getwd()
+	r, err := LoadLayers(fromFlag, dir)
+	for _, w := range r.Warnings {
+		fmt.Fprintf(os.Stderr, "void: config: %s\n", w)
 	}
+	return r.Config, r.File(LayerUser), err
+}
+
+// LoadFile decodes the single file at path over the defaults and validates
+// the result.
+func LoadFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Default(), err
+	}
+	return Parse(path, data)
+}
 
-	if err := decodeSimpleTOML(path, &cfg); err != nil {
-		return cfg, path, fmt.Errorf("decode config: %w", err)
+// Parse decodes TOML data over the defaults and validates the result. name
+// prefixes any error.
+func Parse(name string, data []byte) (Config, error) {
+	cfg := Default()
+	if err := decode(name, data, &cfg); err != nil {
+		return cfg, err
 	}
 	cfg.History.Path = expandHome(cfg.History.Path)
 
 	if err := validate(cfg); err != nil {
-		return cfg, path, err
+		return cfg, fmt.Errorf("%s: %w", name, err)
 	}
+	return cfg, nil
+}
 
-	return cfg, path, nil
+// UserFile returns the user config file that Load would read, or the default
+// location when there is none yet.
+func UserFile(fromFlag string) (string, error) {
+	if path := resolveConfigPath(fromFlag); path != "" {
+		return path, nil
+	}
+	if fromFlag != "" {
+		return fromFlag, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".void", "config.toml"), nil
 }
 
 func resolveConfigPath(fromFlag string) string {
@@ -104,96 +207,93 @@ func expandHome(path string) string {
 
 func validate(cfg Config) error {
 	if strings.TrimSpace(cfg.Shell.Executable) == "" {
-		return errors.New("shell.executable cannot be empty")
+		return &keyError{"shell.executable", errors.New("shell.executable cannot be empty")}
 	}
-	if cfg.History.MaxSize <= 0 {
-		return errors.New("history.max_size must be greater than zero")
+	switch cfg.Prompt.ColorMode {
+	case "auto", "truecolor", "256", "16":
+	default:
+		return &keyError{"prompt.color_mode", fmt.Errorf("prompt.color_mode must be \"auto\", \"truecolor\", \"256\" or \"16\", got %q", cfg.Prompt.ColorMode)}
 	}
-	if cfg.History.Path == "" {
-		return errors.New("history.path cannot be empty")
+	switch cfg.Prompt.PathColors {
+	case "gradient", "interpolate", "hash":
+	default:
+		return &keyError{"prompt.path_colors", fmt.Errorf("prompt.path_colors must be \"gradient\", \"interpolate\" or \"hash\", got %q", cfg.Prompt.PathColors)}
 	}
-	return nil
-}
-
-func decodeSimpleTOML(path string, cfg *Config) error {
-	f, err := os.Open(path)
-	if err != nil {
-		return err
+	if cfg.Prompt.Timeout <= 0 {
+		return &keyError{"prompt.timeout", errors.New("prompt.timeout must be greater than zero")}
 	}
-	defer f.Close()
-
-	section := ""
-	s := bufio.NewScanner(f)
-	for s.Scan() {
-		line := strings.TrimSpace(s.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	for _, name := range cfg.Prompt.GitIndicators {
+		switch name {
+		case "ahead", "behind", "staged", "modified", "untracked", "conflicted", "stash", "operation":
+		default:
+			return &keyError{"prompt.git_indicators", fmt.Errorf("prompt.git_indicators: unknown indicator %q", name)}
 		}
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			section = strings.Trim(line, "[]")
-			continue
+	}
+	switch cfg.Prompt.Notify {
+	case "off", "bell", "desktop":
+	default:
+		return &keyError{"prompt.notify", fmt.Errorf("prompt.notify must be \"off\", \"bell\" or \"desktop\", got %q", cfg.Prompt.Notify)}
+	}
+	if cfg.Prompt.NotifyAfter < 0 {
+		return &keyError{"prompt.notify_after", errors.New("prompt.notify_after cannot be negative")}
+	}
+	for name, s := range cfg.Segment {
+		if !slices.Contains(segmentNames, name) {
+			key := "segment." + name
+			return &keyError{key, fmt.Errorf("%s: unknown segment %q (known: %s)", key, name, strings.Join(segmentNames, ", "))}
 		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
+		if s.Timeout < 0 {
+			key := "segment." + name + ".timeout"
+			return &keyError{key, fmt.Errorf("%s cannot be negative", key)}
 		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		value = strings.Trim(value, "\"")
-
-		switch section {
-		case "":
-			if key == "preset" {
-				cfg.Preset = value
-			}
-		case "shell":
-			switch key {
-			case "executable":
-				cfg.Shell.Executable = value
-			case "args":
-				cfg.Shell.Args = parseArray(value)
-			}
-		case "prompt":
-			switch key {
-			case "symbol":
-				cfg.Prompt.Symbol = value
-			case "segments":
-				cfg.Prompt.Segments = parseArray(value)
-			}
-		case "history":
-			switch key {
-			case "path":
-				cfg.History.Path = value
-			case "max_size":
-				max, err := strconv.Atoi(value)
-				if err != nil {
-					return fmt.Errorf("invalid history.max_size: %w", err)
-				}
-				cfg.History.MaxSize = max
-			}
-		case "alias":
-			cfg.Alias[key] = strings.Trim(value, "\"")
-		case "palette":
-			cfg.Palette[key] = strings.Trim(value, "\"")
-		case "api":
-			switch key {
-			case "alpha_vantage":
-				cfg.API.AlphaVantage = value
-			}
+		if s.Threshold < 0 {
+			key := "segment." + name + ".threshold"
+			return &keyError{key, fmt.Errorf("%s cannot be negative", key)}
+		}
+		if s.MaxLength < 0 {
+			key := "segment." + name + ".max_length"
+			return &keyError{key, fmt.Errorf("%s cannot be negative", key)}
+		}
+		if !validWhen(s.When) {
+			key := "segment." + name + ".when"
+			return &keyError{key, fmt.Errorf("%s must be \"always\", \"never\", \"error\", \"success\" or \"env:NAME\", got %q", key, s.When)}
+		}
+	}
+	if cfg.History.MaxSize <= 0 {
+		return &keyError{"history.max_size", errors.New("history.max_size must be greater than zero")}
+	}
+	if cfg.History.Path == "" {
+		return &keyError{"history.path", errors.New("history.path cannot be empty")}
+	}
+	if cfg.History.RedactMode != "mask" && cfg.History.RedactMode != "drop" {
+		return &keyError{"history.redact_mode", fmt.Errorf("history.redact_mode must be \"mask\" or \"drop\", got %q", cfg.History.RedactMode)}
+	}
+	for i, pattern := range cfg.History.Redact {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return &keyError{"history.redact", fmt.Errorf("history.redact[%d]: %w", i, err)}
 		}
 	}
-	return s.Err()
+	return nil
 }
 
-func parseArray(value string) []string {
-	value = strings.TrimSpace(strings.Trim(value, "[]"))
-	if value == "" {
-		return nil
-	}
-	parts := strings.Split(value, ",")
-	out := make([]string, 0, len(parts))
-	for _, p := range parts {
-		out = append(out, strings.Trim(strings.TrimSpace(p), "\""))
+// segmentNames are the prompt segments a [segment.<name>] table can style.
+var segmentNames = []string{"user", "git", "path", "time", "exit_code", "duration", "symbol", "go", "node", "python", "rust", "java"}
+
+func validWhen(when string) bool {
+	switch when {
+	case "", "always", "never", "error", "success":
+		return true
 	}
-	return out
+	name, ok := strings.CutPrefix(when, "env:")
+	return ok && name != ""
+}
+
+// keyError is a validation error for the value of key.
+type keyError struct {
+	key string
+	err error
 }
+
+func (e *keyError) Error() string { return e.err.Error() }
+
+func (e *keyError) Unwrap() error { return e.err }
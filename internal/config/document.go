@@ -0,0 +1,372 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Document is a config file edited line by line, so comments, blank lines
+// and the order of keys survive Set and Unset.
+type Document struct {
+	lines []string
+	bom   bool
+	crlf  bool
+}
+
+// ParseDocument splits data into lines. It does not validate the TOML.
+func ParseDocument(data []byte) *Document {
+	d := &Document{}
+	if rest, ok := bytes.CutPrefix(data, []byte("\xef\xbb\xbf")); ok {
+		d.bom, data = true, rest
+	}
+	text := string(data)
+	d.crlf = strings.Contains(text, "\r\n")
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text != "" {
+		d.lines = strings.Split(text, "\n")
+	}
+	return d
+}
+
+// ReadDocument reads the file at path; a missing file is an empty document.
+func ReadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return ParseDocument(data), nil
+}
+
+// Bytes renders the document with its original BOM and line endings.
+func (d *Document) Bytes() []byte {
+	var b bytes.Buffer
+	if d.bom {
+		b.WriteString("\xef\xbb\xbf")
+	}
+	eol := "\n"
+	if d.crlf {
+		eol = "\r\n"
+	}
+	for _, line := range d.lines {
+		b.WriteString(line)
+		b.WriteString(eol)
+	}
+	return b.Bytes()
+}
+
+// Set assigns value to the dotted key, replacing the existing assignment in
+// place or adding one to the key's table. value is parsed like an
+// environment override: lists are comma-separated or a TOML array.
+func (d *Document) Set(key, value string) error {
+	scratch := Default()
+	if err := setString(&scratch, key, value); err != nil {
+		return err
+	}
+	v, _ := Get(scratch, key)
+	literal := FormatValue(v)
+
+	path := keyPath(key)
+	if start, end, ok := d.find(path); ok {
+		line := d.lines[start]
+		eq := keyEnd(line)
+		out := strings.TrimRight(line[:eq], " \t") + " = " + literal
+		if start == end {
+			if c := commentIndex(line, eq+1); c != -1 {
+				out += " " + line[c:]
+			}
+		}
+		d.replace(start, end, out)
+		return nil
+	}
+
+	table, name := path[:len(path)-1], path[len(path)-1]
+	assignment := formatKey(name) + " = " + literal
+	if len(table) == 0 {
+		at := len(d.lines)
+		for i, line := range d.lines {
+			if _, ok := tableHeader(line); ok {
+				at = i
+				break
+			}
+		}
+		for at > 0 && at < len(d.lines) && isBlankOrComment(d.lines[at-1]) {
+			at--
+		}
+		d.insert(at, assignment)
+		return nil
+	}
+	if at, ok := d.tableEnd(table); ok {
+		d.insert(at, assignment)
+		return nil
+	}
+	if n := len(d.lines); n > 0 && strings.TrimSpace(d.lines[n-1]) != "" {
+		d.lines = append(d.lines, "")
+	}
+	d.lines = append(d.lines, "["+formatKey(table...)+"]", assignment)
+	return nil
+}
+
+// Unset removes the assignment of key and reports whether there was one.
+func (d *Document) Unset(key string) (bool, error) {
+	if !validKey(key) {
+		return false, fmt.Errorf("unknown key %q", key)
+	}
+	start, end, ok := d.find(keyPath(key))
+	if ok {
+		d.replace(start, end)
+	}
+	return ok, nil
+}
+
+// Save validates the document and writes it to path through a temporary
+// file. An invalid document is not written.
+func (d *Document) Save(path string) error {
+	data := d.Bytes()
+	if _, err := Parse(path, data); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *Document) replace(start, end int, lines ...string) {
+	d.lines = append(d.lines[:start], append(lines, d.lines[end+1:]...)...)
+}
+
+func (d *Document) insert(at int, line string) {
+	d.replace(at, at-1, line)
+}
+
+// find returns the first and last line of the assignment of path.
+func (d *Document) find(path []string) (int, int, bool) {
+	var table []string
+	for i := 0; i < len(d.lines); i++ {
+		line := d.lines[i]
+		if t, ok := tableHeader(line); ok {
+			table = t
+			continue
+		}
+		eq := keyEnd(line)
+		if eq == -1 {
+			continue
+		}
+		end := valueEnd(d.lines, i, eq+1)
+		if equalPath(append(append([]string{}, table...), splitKey(line[:eq])...), path) {
+			return i, end, true
+		}
+		i = end
+	}
+	return 0, 0, false
+}
+
+// tableEnd returns the line after the last assignment in table.
+func (d *Document) tableEnd(table []string) (int, bool) {
+	at, found, inTable := 0, false, false
+	for i := 0; i < len(d.lines); i++ {
+		line := d.lines[i]
+		if t, ok := tableHeader(line); ok {
+			inTable = equalPath(t, table)
+			if inTable && !found {
+				at, found = i+1, true
+			}
+			continue
+		}
+		eq := keyEnd(line)
+		if eq == -1 {
+			continue
+		}
+		end := valueEnd(d.lines, i, eq+1)
+		if inTable {
+			at = end + 1
+		}
+		i = end
+	}
+	return at, found
+}
+
+// keyPath splits a dotted config key into TOML path elements. Palette and
+// alias names are kept whole even when they contain dots.
+func keyPath(key string) []string {
+	if table, name, ok := splitMapKey(key); ok {
+		return []string{table, name}
+	}
+	return strings.Split(key, ".")
+}
+
+func equalPath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tableHeader parses a "[table]" line.
+func tableHeader(line string) ([]string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") || strings.HasPrefix(line, "[[") {
+		return nil, false
+	}
+	end := commentIndex(line, 0)
+	if end == -1 {
+		end = len(line)
+	}
+	line = strings.TrimSpace(line[:end])
+	if !strings.HasSuffix(line, "]") {
+		return nil, false
+	}
+	return splitKey(line[1 : len(line)-1]), true
+}
+
+// splitKey splits a possibly quoted, dotted TOML key.
+func splitKey(s string) []string {
+	var parts []string
+	var cur strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' && i+1 < len(s) {
+				i++
+				cur.WriteByte(s[i])
+			} else if c == quote {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			parts = append(parts, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		case c != ' ' && c != '\t':
+			cur.WriteByte(c)
+		}
+	}
+	return append(parts, strings.TrimSpace(cur.String()))
+}
+
+func formatKey(parts ...string) string {
+	out := make([]string, len(parts))
+	for i, p := range parts {
+		out[i] = p
+		if p == "" || strings.IndexFunc(p, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+		}) != -1 {
+			out[i] = quote(p)
+		}
+	}
+	return strings.Join(out, ".")
+}
+
+// keyEnd returns the index of the "=" of a key/value line, or -1.
+func keyEnd(line string) int {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '[' {
+		return -1
+	}
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+func isBlankOrComment(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "" || line[0] == '#'
+}
+
+// scanner tracks strings and brackets across the lines of a value.
+type scanner struct {
+	quote string
+	depth int
+}
+
+// scan reads line from index from and returns where a comment starts, or -1.
+func (s *scanner) scan(line string, from int) int {
+	for j := from; j < len(line); j++ {
+		c := line[j]
+		if s.quote != "" {
+			if c == '\\' && s.quote[0] == '"' {
+				j++
+			} else if strings.HasPrefix(line[j:], s.quote) {
+				j += len(s.quote) - 1
+				s.quote = ""
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			s.quote = string(c)
+			if triple := strings.Repeat(s.quote, 3); strings.HasPrefix(line[j:], triple) {
+				s.quote = triple
+				j += 2
+			}
+		case '[', '{':
+			s.depth++
+		case ']', '}':
+			s.depth--
+		case '#':
+			return j
+		}
+	}
+	if len(s.quote) == 1 {
+		s.quote = ""
+	}
+	return -1
+}
+
+func commentIndex(line string, from int) int {
+	var s scanner
+	return s.scan(line, from)
+}
+
+// valueEnd returns the last line of the value starting at lines[i][col:],
+// following multi-line arrays and strings.
+func valueEnd(lines []string, i, col int) int {
+	var s scanner
+	for ; i < len(lines); i, col = i+1, 0 {
+		s.scan(lines[i], col)
+		if s.depth <= 0 && s.quote == "" {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// fileConfig mirrors the TOML layout. Pointer and nil-able fields tell keys
+// that are absent from the file apart from zero values.
+type fileConfig struct {
+	Preset  *string                `toml:"preset"`
+	Palette map[string]string      `toml:"palette"`
+	Shell   *fileShell             `toml:"shell"`
+	Prompt  *filePrompt            `toml:"prompt"`
+	History *fileHistory           `toml:"history"`
+	Alias   map[string]string      `toml:"alias"`
+	API     *fileAPI               `toml:"api"`
+	Segment map[string]fileSegment `toml:"segment"`
+}
+
+type fileShell struct {
+	Executable *string  `toml:"executable"`
+	Args       []string `toml:"args"`
+}
+
+type filePrompt struct {
+	Symbol        *string  `toml:"symbol"`
+	Segments      []string `toml:"segments"`
+	RightSegments []string `toml:"right_segments"`
+	Transient     *bool    `toml:"transient"`
+	ColorMode     *string  `toml:"color_mode"`
+	PathColors    *string  `toml:"path_colors"`
+	Timeout       *int     `toml:"timeout"`
+	GitIndicators []string `toml:"git_indicators"`
+	Notify        *string  `toml:"notify"`
+	NotifyAfter   *int     `toml:"notify_after"`
+}
+
+type fileSegment struct {
+	Format     *string `toml:"format"`
+	Icon       *string `toml:"icon"`
+	FG         *string `toml:"fg"`
+	BG         *string `toml:"bg"`
+	MaxLength  *int    `toml:"max_length"`
+	When       *string `toml:"when"`
+	TimeFormat *string `toml:"time_format"`
+	Timeout    *int    `toml:"timeout"`
+	Threshold  *int    `toml:"threshold"`
+}
+
+type fileHistory struct {
+	Path          *string  `toml:"path"`
+	MaxSize       *int     `toml:"max_size"`
+	IgnoreSpace   *bool    `toml:"ignore_space"`
+	Redact        []string `toml:"redact"`
+	RedactMode    *string  `toml:"redact_mode"`
+	DetectSecrets *bool    `toml:"detect_secrets"`
+}
+
+type fileAPI struct {
+	AlphaVantage *string `toml:"alpha_vantage"`
+}
+
+// decode decodes TOML data read from name onto cfg. Unknown keys and values
+// of the wrong type are errors reported as name:line:column.
+func decode(name string, data []byte, cfg *Config) error {
+	var fc fileConfig
+	if err := decodeTOML(data, &fc); err != nil {
+		return positionError(name, data, err)
+	}
+	fc.apply(cfg)
+	return nil
+}
+
+// DecodeFile strictly decodes the TOML file at path into v, reporting
+// problems the same way as config files.
+func DecodeFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return decodeBytes(path, data, v)
+}
+
+// DecodeFS is DecodeFile for a file in fsys, such as an embedded one.
+func DecodeFS(fsys fs.FS, name string, v any) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	return decodeBytes(name, data, v)
+}
+
+// Decode is DecodeFile for TOML read from r; name labels any errors.
+func Decode(name string, r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return decodeBytes(name, data, v)
+}
+
+func decodeBytes(name string, data []byte, v any) error {
+	if err := decodeTOML(data, v); err != nil {
+		return positionError(name, data, err)
+	}
+	return nil
+}
+
+func decodeTOML(data []byte, v any) error {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	dec := toml.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func (fc fileConfig) apply(cfg *Config) {
+	if fc.Preset != nil {
+		cfg.Preset = *fc.Preset
+	}
+	for k, v := range fc.Palette {
+		cfg.Palette[k] = v
+	}
+	for k, v := range fc.Alias {
+		cfg.Alias[k] = v
+	}
+	if s := fc.Shell; s != nil {
+		if s.Executable != nil {
+			cfg.Shell.Executable = *s.Executable
+		}
+		if s.Args != nil {
+			cfg.Shell.Args = s.Args
+		}
+	}
+	if p := fc.Prompt; p != nil {
+		if p.Symbol != nil {
+			cfg.Prompt.Symbol = *p.Symbol
+		}
+		if p.Segments != nil {
+			cfg.Prompt.Segments = p.Segments
+		}
+		if p.RightSegments != nil {
+			cfg.Prompt.RightSegments = p.RightSegments
+		}
+		if p.Transient != nil {
+			cfg.Prompt.Transient = *p.Transient
+		}
+		if p.ColorMode != nil {
+			cfg.Prompt.ColorMode = *p.ColorMode
+		}
+		if p.PathColors != nil {
+			cfg.Prompt.PathColors = *p.PathColors
+		}
+		if p.Timeout != nil {
+			cfg.Prompt.Timeout = *p.Timeout
+		}
+		if p.GitIndicators != nil {
+			cfg.Prompt.GitIndicators = p.GitIndicators
+		}
+		if p.Notify != nil {
+			cfg.Prompt.Notify = *p.Notify
+		}
+		if p.NotifyAfter != nil {
+			cfg.Prompt.NotifyAfter = *p.NotifyAfter
+		}
+	}
+	if h := fc.History; h != nil {
+		if h.Path != nil {
+			cfg.History.Path = *h.Path
+		}
+		if h.MaxSize != nil {
+			cfg.History.MaxSize = *h.MaxSize
+		}
+		if h.IgnoreSpace != nil {
+			cfg.History.IgnoreSpace = *h.IgnoreSpace
+		}
+		if h.Redact != nil {
+			cfg.History.Redact = h.Redact
+		}
+		if h.RedactMode != nil {
+			cfg.History.RedactMode = *h.RedactMode
+		}
+		if h.DetectSecrets != nil {
+			cfg.History.DetectSecrets = *h.DetectSecrets
+		}
+	}
+	if a := fc.API; a != nil && a.AlphaVantage != nil {
+		cfg.API.AlphaVantage = *a.AlphaVantage
+	}
+	for name, fs := range fc.Segment {
+		s := cfg.Segment[name]
+		if fs.Format != nil {
+			s.Format = *fs.Format
+		}
+		if fs.Icon != nil {
+			s.Icon = *fs.Icon
+		}
+		if fs.FG != nil {
+			s.FG = *fs.FG
+		}
+		if fs.BG != nil {
+			s.BG = *fs.BG
+		}
+		if fs.MaxLength != nil {
+			s.MaxLength = *fs.MaxLength
+		}
+		if fs.When != nil {
+			s.When = *fs.When
+		}
+		if fs.TimeFormat != nil {
+			s.TimeFormat = *fs.TimeFormat
+		}
+		if fs.Timeout != nil {
+			s.Timeout = *fs.Timeout
+		}
+		if fs.Threshold != nil {
+			s.Threshold = *fs.Threshold
+		}
+		cfg.Segment[name] = s
+	}
+}
+
+var typeMismatch = regexp.MustCompile(`cannot decode TOML (\w+) into .* of type (\S+)`)
+
+// positionError rewrites go-toml errors as "path:line:col: message", one
+// line per problem, naming the dotted key instead of Go types.
+func positionError(path string, data []byte, err error) error {
+	var strict *toml.StrictMissingError
+	if errors.As(err, &strict) {
+		errs := make([]error, 0, len(strict.Errors))
+		for _, e := range strict.Errors {
+			row, col := e.Position()
+			errs = append(errs, fmt.Errorf("%s:%d:%d: unknown key %q", path, row, col, strings.Join(e.Key(), ".")))
+		}
+		return errors.Join(errs...)
+	}
+	var decodeErr *toml.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	row, col := decodeErr.Position()
+	msg := strings.TrimPrefix(decodeErr.Error(), "toml: ")
+	if m := typeMismatch.FindStringSubmatch(msg); m != nil {
+		msg = fmt.Sprintf("expected %s, got %s", tomlTypeName(m[2]), m[1])
+		if key := keyAt(data, row); key != "" {
+			msg = key + ": " + msg
+		}
+	}
+	return fmt.Errorf("%s:%d:%d: %s", path, row, col, msg)
+}
+
+func tomlTypeName(goType string) string {
+	goType = strings.TrimPrefix(goType, "*")
+	switch {
+	case goType == "int":
+		return "integer"
+	case goType == "bool":
+		return "boolean"
+	case goType == "string", strings.HasPrefix(goType, "map[string]string"):
+		return "string"
+	case strings.HasPrefix(goType, "[]"):
+		return "array of " + tomlTypeName(goType[2:]) + "s"
+	case strings.Contains(goType, "."):
+		return "table"
+	}
+	return goType
+}
+
+// keyAt returns the dotted key assigned on line row (1-based), prefixed with
+// the table it belongs to.
+func keyAt(data []byte, row int) string {
+	lines := strings.Split(string(data), "\n")
+	if row < 1 || row > len(lines) {
+		return ""
+	}
+	eq := strings.Index(lines[row-1], "=")
+	if eq == -1 {
+		return ""
+	}
+	key := strings.Trim(strings.TrimSpace(lines[row-1][:eq]), `"'`)
+	for i := row - 2; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "[") {
+			if end := strings.Index(line, "]"); end != -1 {
+				return strings.Trim(line[:end], "[ ") + "." + key
+			}
+		}
+	}
+	return key
+}
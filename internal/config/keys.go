@@ -0,0 +1,327 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// field is a scalar or list key addressed by its dotted TOML name.
+type field struct {
+	key string
+	ptr func(*Config) any
+}
+
+var fields = []field{
+	{"preset", func(c *Config) any { return &c.Preset }},
+	{"shell.executable", func(c *Config) any { return &c.Shell.Executable }},
+	{"shell.args", func(c *Config) any { return &c.Shell.Args }},
+	{"prompt.symbol", func(c *Config) any { return &c.Prompt.Symbol }},
+	{"prompt.segments", func(c *Config) any { return &c.Prompt.Segments }},
+	{"prompt.right_segments", func(c *Config) any { return &c.Prompt.RightSegments }},
+	{"prompt.transient", func(c *Config) any { return &c.Prompt.Transient }},
+	{"prompt.color_mode", func(c *Config) any { return &c.Prompt.ColorMode }},
+	{"prompt.path_colors", func(c *Config) any { return &c.Prompt.PathColors }},
+	{"prompt.timeout", func(c *Config) any { return &c.Prompt.Timeout }},
+	{"prompt.git_indicators", func(c *Config) any { return &c.Prompt.GitIndicators }},
+	{"prompt.notify", func(c *Config) any { return &c.Prompt.Notify }},
+	{"prompt.notify_after", func(c *Config) any { return &c.Prompt.NotifyAfter }},
+	{"history.path", func(c *Config) any { return &c.History.Path }},
+	{"history.max_size", func(c *Config) any { return &c.History.MaxSize }},
+	{"history.ignore_space", func(c *Config) any { return &c.History.IgnoreSpace }},
+	{"history.redact", func(c *Config) any { return &c.History.Redact }},
+	{"history.redact_mode", func(c *Config) any { return &c.History.RedactMode }},
+	{"history.detect_secrets", func(c *Config) any { return &c.History.DetectSecrets }},
+	{"api.alpha_vantage", func(c *Config) any { return &c.API.AlphaVantage }},
+}
+
+// segmentFields are the keys of a [segment.<name>] table.
+var segmentFields = []struct {
+	name string
+	ptr  func(*SegmentConfig) any
+}{
+	{"format", func(s *SegmentConfig) any { return &s.Format }},
+	{"icon", func(s *SegmentConfig) any { return &s.Icon }},
+	{"fg", func(s *SegmentConfig) any { return &s.FG }},
+	{"bg", func(s *SegmentConfig) any { return &s.BG }},
+	{"max_length", func(s *SegmentConfig) any { return &s.MaxLength }},
+	{"when", func(s *SegmentConfig) any { return &s.When }},
+	{"time_format", func(s *SegmentConfig) any { return &s.TimeFormat }},
+	{"timeout", func(s *SegmentConfig) any { return &s.Timeout }},
+	{"threshold", func(s *SegmentConfig) any { return &s.Threshold }},
+}
+
+// mapTables are the tables whose keys are free-form names.
+var mapTables = []string{"palette", "alias"}
+
+func lookupField(key string) (field, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func tableMap(cfg *Config, table string) map[string]string {
+	switch table {
+	case "palette":
+		return cfg.Palette
+	case "alias":
+		return cfg.Alias
+	}
+	return nil
+}
+
+// splitMapKey splits "palette.name" into its table and name.
+func splitMapKey(key string) (string, string, bool) {
+	table, name, ok := strings.Cut(key, ".")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	for _, t := range mapTables {
+		if t == table {
+			return table, name, true
+		}
+	}
+	return "", "", false
+}
+
+// splitSegmentKey splits "segment.<name>.<field>" into the segment name and
+// field index.
+func splitSegmentKey(key string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(key, "segment.")
+	if !ok {
+		return "", 0, false
+	}
+	dot := strings.LastIndex(rest, ".")
+	if dot <= 0 {
+		return "", 0, false
+	}
+	for i, f := range segmentFields {
+		if f.name == rest[dot+1:] {
+			return rest[:dot], i, true
+		}
+	}
+	return "", 0, false
+}
+
+// validKey reports whether key names a config value.
+func validKey(key string) bool {
+	if _, ok := lookupField(key); ok {
+		return true
+	}
+	if _, _, ok := splitMapKey(key); ok {
+		return true
+	}
+	_, _, ok := splitSegmentKey(key)
+	return ok
+}
+
+// Keys lists every key set in cfg: the fixed keys in file order, then
+// palette and alias entries sorted by name, then the non-empty segment
+// settings.
+func Keys(cfg Config) []string {
+	keys := make([]string, 0, len(fields)+len(cfg.Palette)+len(cfg.Alias))
+	for _, f := range fields {
+		keys = append(keys, f.key)
+	}
+	for _, table := range mapTables {
+		m := tableMap(&cfg, table)
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			keys = append(keys, table+"."+name)
+		}
+	}
+	names := make([]string, 0, len(cfg.Segment))
+	for name := range cfg.Segment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := cfg.Segment[name]
+		for _, f := range segmentFields {
+			if v := deref(f.ptr(&s)); v != "" && v != 0 {
+				keys = append(keys, "segment."+name+"."+f.name)
+			}
+		}
+	}
+	return keys
+}
+
+func deref(p any) any {
+	switch p := p.(type) {
+	case *string:
+		return *p
+	case *int:
+		return *p
+	case *bool:
+		return *p
+	case *[]string:
+		return *p
+	}
+	return nil
+}
+
+// Get returns the value of key in cfg: a string, int, bool or []string.
+func Get(cfg Config, key string) (any, bool) {
+	if f, ok := lookupField(key); ok {
+		return deref(f.ptr(&cfg)), true
+	}
+	if table, name, ok := splitMapKey(key); ok {
+		v, ok := tableMap(&cfg, table)[name]
+		return v, ok
+	}
+	if name, i, ok := splitSegmentKey(key); ok {
+		s, ok := cfg.Segment[name]
+		if !ok {
+			return nil, false
+		}
+		return deref(segmentFields[i].ptr(&s)), true
+	}
+	return nil, false
+}
+
+// setString parses value for key and stores it in cfg. Lists are
+// comma-separated or a TOML array.
+func setString(cfg *Config, key, value string) error {
+	if table, name, ok := splitMapKey(key); ok {
+		tableMap(cfg, table)[name] = value
+		return nil
+	}
+	if name, i, ok := splitSegmentKey(key); ok {
+		if cfg.Segment == nil {
+			cfg.Segment = map[string]SegmentConfig{}
+		}
+		s := cfg.Segment[name]
+		if err := setValue(segmentFields[i].ptr(&s), key, value); err != nil {
+			return err
+		}
+		cfg.Segment[name] = s
+		return nil
+	}
+	f, ok := lookupField(key)
+	if !ok {
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return setValue(f.ptr(cfg), key, value)
+}
+
+func setValue(ptr any, key, value string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: expected integer, got %q", key, value)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: expected boolean, got %q", key, value)
+		}
+		*p = b
+	case *[]string:
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var v struct{ List []string }
+			if err := toml.Unmarshal([]byte("List = "+value), &v); err != nil {
+				return fmt.Errorf("%s: expected array of strings, got %s", key, value)
+			}
+			*p = append([]string{}, v.List...)
+			return nil
+		}
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*p = list
+	}
+	return nil
+}
+
+// FormatValue renders v as a TOML value.
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return quote(v)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		items := make([]string, len(v))
+		for i, s := range v {
+			items[i] = quote(s)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+// quote renders s as a TOML basic string.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Change is a key whose effective value differs from its default. Default or
+// Value is nil when the key has no value on that side.
+type Change struct {
+	Key     string
+	Default any
+	Value   any
+}
+
+// Diff lists the keys of cfg that differ from the built-in defaults.
+func Diff(cfg Config) []Change {
+	base := Default()
+	base.History.Path = expandHome(base.History.Path)
+	var changes []Change
+	seen := map[string]bool{}
+	for _, key := range append(Keys(base), Keys(cfg)...) {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		c := Change{Key: key}
+		if v, ok := Get(base, key); ok {
+			c.Default = v
+		}
+		if v, ok := Get(cfg, key); ok {
+			c.Value = v
+		}
+		if c.Default != nil && c.Value != nil && FormatValue(c.Default) == FormatValue(c.Value) {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	LayerDefault = "default"
+	LayerSystem  = "system"
+	LayerUser    = "user"
+	LayerProject = "project"
+	LayerEnv     = "env"
+
+	projectFileName = ".void.toml"
+	envPrefix       = "VOID_"
+)
+
+// Source is the layer that set a value, with the file or environment
+// variable it came from.
+type Source struct {
+	Layer string
+	Path  string
+}
+
+func (s Source) String() string {
+	if s.Path == "" {
+		return s.Layer
+	}
+	return s.Layer + " (" + s.Path + ")"
+}
+
+// Resolved is the configuration merged from every layer.
+type Resolved struct {
+	Config Config
+	// Files are the config files that were applied, lowest precedence first.
+	Files []Source
+	// Origins maps each dotted key to the layer that last set it.
+	Origins map[string]Source
+	// Warnings describe settings that were skipped rather than applied.
+	Warnings []string
+}
+
+// File returns the path of the applied file from layer, or "".
+func (r Resolved) File(layer string) string {
+	for _, f := range r.Files {
+		if f.Layer == layer {
+			return f.Path
+		}
+	}
+	return ""
+}
+
+// LoadLayers merges the built-in defaults, the system file, the user file
+// (fromFlag or the usual locations), the nearest .void.toml at or above dir
+// and VOID_* environment variables. Each layer overrides only the keys it
+// sets.
+func LoadLayers(fromFlag, dir string) (Resolved, error) {
+	r := Resolved{Config: Default(), Origins: map[string]Source{}}
+	for _, key := range Keys(r.Config) {
+		r.Origins[key] = Source{Layer: LayerDefault}
+	}
+
+	var files []Source
+	if path := systemConfigPath(); path != "" && fileExists(path) {
+		files = append(files, Source{Layer: LayerSystem, Path: path})
+	}
+	if path := resolveConfigPath(fromFlag); path != "" {
+		files = append(files, Source{Layer: LayerUser, Path: path})
+	}
+	if path := findProjectFile(dir); path != "" {
+		files = append(files, Source{Layer: LayerProject, Path: path})
+	}
+	for _, src := range files {
+		keys, skipped, err := applyFile(src, &r.Config)
+		if err != nil {
+			return r, err
+		}
+		if len(skipped) > 0 {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("%s: ignoring %s; a project file may only set preset, prompt.*, palette.* and segment.*", src.Path, strings.Join(skipped, ", ")))
+		}
+		for _, key := range keys {
+			r.Origins[key] = src
+		}
+		r.Files = append(r.Files, src)
+	}
+
+	if err := applyEnv(&r.Config, r.Origins); err != nil {
+		return r, err
+	}
+	r.Config.History.Path = expandHome(r.Config.History.Path)
+	r.Config.explicit = map[string]bool{}
+	for key, src := range r.Origins {
+		if src.Layer != LayerDefault {
+			r.Config.explicit[key] = true
+		}
+	}
+
+	if err := validate(r.Config); err != nil {
+		var ke *keyError
+		if errors.As(err, &ke) && r.Origins[ke.key].Path != "" {
+			return r, fmt.Errorf("%s: %w", r.Origins[ke.key].Path, err)
+		}
+		return r, err
+	}
+	return r, nil
+}
+
+// LayerFiles lists the files LoadLayers reads for fromFlag and dir. The
+// system and user files are listed even when they do not exist yet, so a
+// caller watching them notices when they are created.
+func LayerFiles(fromFlag, dir string) []string {
+	var files []string
+	if path := systemConfigPath(); path != "" {
+		files = append(files, path)
+	}
+	if path, err := UserFile(fromFlag); err == nil {
+		files = append(files, path)
+	}
+	if path := findProjectFile(dir); path != "" {
+		files = append(files, path)
+	}
+	return files
+}
+
+// applyFile decodes the file of src onto cfg and returns the keys it set.
+// Keys a project file may not set are left out and returned as skipped.
+func applyFile(src Source, cfg *Config) (keys, skipped []string, err error) {
+	data, err := os.ReadFile(src.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw map[string]any
+	if err := toml.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &raw); err != nil {
+		return nil, nil, positionError(src.Path, data, err)
+	}
+	flattenKeys("", raw, &keys)
+	sort.Strings(keys)
+	if src.Layer == LayerProject {
+		allowed := keys[:0:0]
+		for _, key := range keys {
+			if projectKey(key) {
+				allowed = append(allowed, key)
+			} else {
+				skipped = append(skipped, key)
+			}
+		}
+		if len(skipped) > 0 {
+			// projectKey depends only on the top-level name.
+			for name := range raw {
+				if !projectKey(name) && !projectKey(name+".") {
+					delete(raw, name)
+				}
+			}
+			if data, err = toml.Marshal(raw); err != nil {
+				return nil, nil, err
+			}
+			keys = allowed
+		}
+	}
+	if err := decode(src.Path, data, cfg); err != nil {
+		return nil, nil, err
+	}
+	return keys, skipped, nil
+}
+
+// projectKey reports whether a .void.toml may set key. Project files come
+// with the repositories you clone, so they only get to change how the
+// prompt looks, never what runs or where history is written.
+func projectKey(key string) bool {
+	if key == "preset" {
+		return true
+	}
+	for _, prefix := range []string{"prompt.", "palette.", "segment."} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func flattenKeys(prefix string, m map[string]any, keys *[]string) {
+	for k, v := range m {
+		if sub, ok := v.(map[string]any); ok {
+			flattenKeys(prefix+k+".", sub, keys)
+			continue
+		}
+		*keys = append(*keys, prefix+k)
+	}
+}
+
+// applyEnv sets keys from VOID_<TABLE>_<KEY> variables, e.g.
+// VOID_PROMPT_SYMBOL or VOID_PALETTE_PATH_FG. Variables that do not name a
+// config key, such as VOID_ACTIVE_LABEL, are left alone.
+func applyEnv(cfg *Config, origins map[string]Source) error {
+	env := os.Environ()
+	sort.Strings(env)
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		key, ok := envKey(name)
+		if !ok {
+			continue
+		}
+		if err := setString(cfg, key, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		origins[key] = Source{Layer: LayerEnv, Path: name}
+	}
+	return nil
+}
+
+func envKey(name string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, envPrefix)
+	if !ok {
+		return "", false
+	}
+	rest = strings.ToLower(rest)
+	for _, f := range fields {
+		if strings.ReplaceAll(f.key, ".", "_") == rest {
+			return f.key, true
+		}
+	}
+	if seg, ok := strings.CutPrefix(rest, "segment_"); ok {
+		// The longest field wins, so TIME_TIME_FORMAT is time.time_format
+		// rather than time_time.format.
+		key := ""
+		field := ""
+		for _, f := range segmentFields {
+			if name, ok := strings.CutSuffix(seg, "_"+f.name); ok && name != "" && len(f.name) > len(field) {
+				key, field = "segment."+name+"."+f.name, f.name
+			}
+		}
+		if key != "" {
+			return key, true
+		}
+	}
+	for _, table := range mapTables {
+		if name, ok := strings.CutPrefix(rest, table+"_"); ok && name != "" {
+			return table + "." + name, true
+		}
+	}
+	return "", false
+}
+
+// findProjectFile returns the nearest .void.toml in dir or its parents.
+func findProjectFile(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, projectFileName)
+		if fileExists(candidate) {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func fileExists(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
@@ -1,6 +1,10 @@
 package config
 
-import "runtime"
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
 
 func defaultShell() string {
 	if runtime.GOOS == "windows" {
@@ -8,3 +12,13 @@ func defaultShell() string {
 	}
 	return "sh"
 }
+
+var systemConfigPath = func() string {
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("ProgramData"); dir != "" {
+			return filepath.Join(dir, "Void", "config.toml")
+		}
+		return ""
+	}
+	return "/etc/void/config.toml"
+}
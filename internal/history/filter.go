@@ -0,0 +1,79 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects records. Zero fields match everything.
+type Filter struct {
+	Dir    string
+	Failed bool
+	Since  time.Time
+	Grep   string
+}
+
+func (f Filter) Match(e Entry) bool {
+	if f.Dir != "" && !sameDir(f.Dir, e.Dir) {
+		return false
+	}
+	if f.Failed && e.ExitCode == 0 {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if f.Grep != "" && !strings.Contains(strings.ToLower(e.Command), strings.ToLower(f.Grep)) {
+		return false
+	}
+	return true
+}
+
+// Select returns the records matching f, oldest first.
+func (s *Store) Select(f Filter) []Entry {
+	var out []Entry
+	for _, e := range s.records {
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ParseSince reads a --since value relative to now: a duration such as
+// "90m", "12h", "3d" or "2w", a date such as "2024-05-01", or an RFC 3339
+// timestamp.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if n := len(value); n > 1 && (value[n-1] == 'd' || value[n-1] == 'w') {
+		if count, err := strconv.Atoi(value[:n-1]); err == nil && count >= 0 {
+			days := count
+			if value[n-1] == 'w' {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use a duration like 2h or 3d, or a date like 2024-05-01)", value)
+}
+
+func sameDir(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSelectFilters(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "history"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	base := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s.Record(Entry{Command: "go test ./...", Time: base, Dir: "/src/app", ExitCode: 1})
+	s.Record(Entry{Command: "git push", Time: base.Add(time.Hour), Dir: "/src/app"})
+	s.Record(Entry{Command: "Go build", Time: base.Add(2 * time.Hour), Dir: "/tmp", ExitCode: 2})
+
+	cases := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "cwd", filter: Filter{Dir: "/src/app/"}, want: []string{"go test ./...", "git push"}},
+		{name: "failed", filter: Filter{Failed: true}, want: []string{"go test ./...", "Go build"}},
+		{name: "since", filter: Filter{Since: base.Add(30 * time.Minute)}, want: []string{"git push", "Go build"}},
+		{name: "grep ignores case", filter: Filter{Grep: "go "}, want: []string{"go test ./...", "Go build"}},
+		{name: "combined", filter: Filter{Dir: "/src/app", Failed: true}, want: []string{"go test ./..."}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := s.Select(tc.filter)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %#v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].Command != tc.want[i] {
+					t.Fatalf("expected %v, got %#v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"90m":                  now.Add(-90 * time.Minute),
+		"3d":                   now.AddDate(0, 0, -3),
+		"2w":                   now.AddDate(0, 0, -14),
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"2024-05-01T08:00:00Z": time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for in, want := range cases {
+		got, err := ParseSince(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("ParseSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseSince("yesterday", now); err == nil {
+		t.Fatal("expected error for unknown value")
+	}
+}
@@ -0,0 +1,105 @@
+package history
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 8
+	bonusFirstChar   = 8
+	bonusConsecutive = 6
+	penaltyGapStart  = 3
+	maxGapPenalty    = 10
+)
+
+// Match is an entry that fuzzy-matched a query. Positions are the rune
+// offsets of the matched characters in Entry.
+type Match struct {
+	Entry     string
+	Score     int
+	Positions []int
+}
+
+// FuzzySearch matches query against entries, which are ordered oldest first,
+// and returns the matches best first. Equal scores favour the most recent
+// entry. An empty query returns every entry, most recent first.
+func FuzzySearch(entries []string, query string) []Match {
+	matches := make([]Match, 0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		score, positions, ok := FuzzyMatch(entries[i], query)
+		if ok {
+			matches = append(matches, Match{Entry: entries[i], Score: score, Positions: positions})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// FuzzyMatch reports whether the runes of query appear in text in order. The
+// score rewards consecutive runs and matches at word boundaries and penalises
+// gaps. Matching ignores case unless query contains an upper-case letter.
+func FuzzyMatch(text, query string) (int, []int, bool) {
+	q := []rune(query)
+	if len(q) == 0 {
+		return 0, nil, true
+	}
+	t := []rune(text)
+	caseSensitive := strings.IndexFunc(query, unicode.IsUpper) != -1
+	eq := func(a, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	best, bestPositions, found := 0, []int(nil), false
+	for start := range t {
+		if !eq(t[start], q[0]) {
+			continue
+		}
+		positions := make([]int, 0, len(q))
+		j := 0
+		for i := start; i < len(t) && j < len(q); i++ {
+			if eq(t[i], q[j]) {
+				positions = append(positions, i)
+				j++
+			}
+		}
+		if j < len(q) {
+			break
+		}
+		if score := scorePositions(t, positions); !found || score > best {
+			best, bestPositions, found = score, positions, true
+		}
+	}
+	return best, bestPositions, found
+}
+
+func scorePositions(text []rune, positions []int) int {
+	score := 0
+	for i, p := range positions {
+		score += scoreMatch
+		if p == 0 || isBoundary(text[p-1]) {
+			score += bonusBoundary
+			if i == 0 {
+				score += bonusFirstChar
+			}
+		}
+		if i == 0 {
+			continue
+		}
+		if gap := p - positions[i-1] - 1; gap == 0 {
+			score += bonusConsecutive
+		} else {
+			score -= penaltyGapStart + min(gap-1, maxGapPenalty)
+		}
+	}
+	return score
+}
+
+func isBoundary(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`/\-_.:=,;|&"'`, r)
+}
@@ -0,0 +1,58 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatchPrefersBoundariesAndRuns(t *testing.T) {
+	score, positions, ok := FuzzyMatch("git checkout main", "gco")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	if !reflect.DeepEqual(positions, []int{0, 4, 9}) {
+		t.Fatalf("unexpected positions %v", positions)
+	}
+
+	scattered, _, _ := FuzzyMatch("go vet ./cmd/foo", "gco")
+	if score <= scattered {
+		t.Fatalf("expected boundary match to outscore scattered one: %d <= %d", score, scattered)
+	}
+
+	if _, _, ok := FuzzyMatch("git status", "gsx"); ok {
+		t.Fatal("expected no match")
+	}
+}
+
+func TestFuzzyMatchPicksBestOccurrence(t *testing.T) {
+	_, positions, ok := FuzzyMatch("make test && go test", "test")
+	if !ok || !reflect.DeepEqual(positions, []int{5, 6, 7, 8}) {
+		t.Fatalf("expected contiguous match, got %v", positions)
+	}
+}
+
+func TestFuzzyMatchSmartCase(t *testing.T) {
+	if _, _, ok := FuzzyMatch("Docker PS", "dps"); !ok {
+		t.Fatal("expected lower-case query to ignore case")
+	}
+	if _, _, ok := FuzzyMatch("docker ps", "Dps"); ok {
+		t.Fatal("expected upper-case query to match case")
+	}
+}
+
+func TestFuzzySearchOrdersByScoreThenRecency(t *testing.T) {
+	entries := []string{"npm test", "go vet ./...", "go test ./...", "echo ok"}
+	matches := FuzzySearch(entries, "test")
+	got := make([]string, 0, len(matches))
+	for _, m := range matches {
+		got = append(got, m.Entry)
+	}
+	if !reflect.DeepEqual(got, []string{"go test ./...", "npm test"}) {
+		t.Fatalf("unexpected order %v", got)
+	}
+
+	all := FuzzySearch(entries, "")
+	if len(all) != 4 || all[0].Entry != "echo ok" || all[3].Entry != "npm test" {
+		t.Fatalf("expected all entries most recent first, got %#v", all)
+	}
+}
@@ -2,85 +2,444 @@ package history
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// formatHeader is the first line of a history file in the current format.
+// Files without it are plain text, one command per line, from older releases.
+const formatHeader = "#void-history v2"
+
+// Entry is one run of a command.
+type Entry struct {
+	Command  string
+	Time     time.Time
+	Dir      string
+	ExitCode int
+	Duration time.Duration
+	Session  string
+}
+
+// Usage records how often and how recently a command was run.
+type Usage struct {
+	Command string
+	Count   int
+	Last    time.Time
+}
+
+type record struct {
+	Command    string    `json:"cmd"`
+	Time       time.Time `json:"time"`
+	Dir        string    `json:"cwd,omitempty"`
+	ExitCode   int       `json:"exit"`
+	DurationMS int64     `json:"duration_ms"`
+	Session    string    `json:"session,omitempty"`
+}
+
+// Store is a history file shared by every running session. Each record is
+// appended under a file lock as soon as it is added; Sync reads what other
+// sessions appended since, and the file is compacted back to maxSize records
+// once it grows past a threshold. maxSize counts runs, so a repeated command
+// takes one record per run.
 type Store struct {
-	path    string
-	maxSize int
-	entries []string
-	seen    map[string]struct{}
+	path     string
+	maxSize  int
+	session  string
+	records  []Entry
+	redactor *Redactor
+
+	// What has been read from the file so far.
+	file        os.FileInfo
+	offset      int64
+	fileRecords int
 }
 
 func New(path string, maxSize int) (*Store, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, err
 	}
-	s := &Store{path: path, maxSize: maxSize, seen: map[string]struct{}{}}
+	s := &Store{path: path, maxSize: maxSize, session: newSessionID()}
 	if err := s.Load(); err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
+// Session identifies the records added through this store.
+func (s *Store) Session() string {
+	return s.session
+}
+
+// Load reads the history file from the start, migrating a plain-text file to
+// the current format.
 func (s *Store) Load() error {
+	return s.withLock(func() error {
+		s.reset()
+		return s.readNew()
+	})
+}
+
+// Sync picks up records other sessions appended since the last read.
+func (s *Store) Sync() error {
+	if fi, err := os.Stat(s.path); err == nil && s.file != nil && os.SameFile(fi, s.file) && fi.Size() == s.offset {
+		return nil
+	}
+	return s.withLock(s.readNew)
+}
+
+// Add records a successful run of cmd in the current directory now.
+func (s *Store) Add(cmd string) error {
+	dir, _ := os.Getwd()
+	return s.Record(Entry{Command: cmd, Time: time.Now(), Dir: dir})
+}
+
+// SetRedactor makes Record mask or drop secrets before anything is stored.
+func (s *Store) SetRedactor(r *Redactor) {
+	s.redactor = r
+}
+
+// Record appends e to the history file, stamping it with this store's session
+// when it has none. e is kept in memory even if the file cannot be written.
+func (s *Store) Record(e Entry) error {
+	if s.redactor != nil {
+		var keep bool
+		if e.Command, keep = s.redactor.Redact(e.Command); !keep {
+			return nil
+		}
+	}
+	if e.Command == "" {
+		return nil
+	}
+	if e.Session == "" {
+		e.Session = s.session
+	}
+	return s.withLock(func() error {
+		readErr := s.readNew()
+		s.append(e)
+		if readErr != nil {
+			return readErr
+		}
+		if err := s.appendToFile(e); err != nil {
+			return err
+		}
+		if s.fileRecords > s.compactThreshold() {
+			return s.compact()
+		}
+		return nil
+	})
+}
+
+// Save folds in other sessions' records and rewrites the file with at most
+// maxSize records.
+func (s *Store) Save() error {
+	return s.withLock(func() error {
+		if err := s.readNew(); err != nil {
+			return err
+		}
+		return s.compact()
+	})
+}
+
+func (s *Store) append(e Entry) {
+	s.records = append(s.records, e)
+	if len(s.records) > s.maxSize {
+		s.records = s.records[len(s.records)-s.maxSize:]
+	}
+}
+
+// Entries returns each distinct command once, ordered by its latest use with
+// the most recent last.
+func (s *Store) Entries() []string {
+	usage := s.Usage()
+	out := make([]string, 0, len(usage))
+	for _, u := range usage {
+		out = append(out, u.Command)
+	}
+	return out
+}
+
+// Records returns every recorded run, oldest first.
+func (s *Store) Records() []Entry {
+	out := make([]Entry, len(s.records))
+	copy(out, s.records)
+	return out
+}
+
+// Usage returns the use count and last use of every command, ordered like
+// Entries.
+func (s *Store) Usage() []Usage {
+	byCommand := map[string]*Usage{}
+	lastIndex := map[string]int{}
+	for i, r := range s.records {
+		u, ok := byCommand[r.Command]
+		if !ok {
+			u = &Usage{Command: r.Command}
+			byCommand[r.Command] = u
+		}
+		u.Count++
+		if r.Time.After(u.Last) {
+			u.Last = r.Time
+		}
+		lastIndex[r.Command] = i
+	}
+	out := make([]Usage, 0, len(byCommand))
+	for _, u := range byCommand {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool { return lastIndex[out[i].Command] < lastIndex[out[j].Command] })
+	return out
+}
+
+// withLock runs fn while holding the lock on the history's lock file. A
+// separate file is locked because compaction replaces the history file.
+func (s *Store) withLock(fn func() error) error {
+	f, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+	return fn()
+}
+
+func (s *Store) reset() {
+	s.records = nil
+	s.file = nil
+	s.offset = 0
+	s.fileRecords = 0
+}
+
+// readNew reads complete records appended after the current offset. A file
+// that was replaced or truncated by another session is read from the start.
+func (s *Store) readNew() error {
 	f, err := os.Open(s.path)
 	if err != nil {
 		if os.IsNotExist(err) {
+			s.file, s.offset, s.fileRecords = nil, 0, 0
 			return nil
 		}
 		return err
 	}
 	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if s.file != nil && (!os.SameFile(fi, s.file) || fi.Size() < s.offset) {
+		s.reset()
+	}
+	s.file = fi
 
-	s.entries = nil
-	s.seen = map[string]struct{}{}
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	if s.offset == 0 {
+		first, err := r.ReadBytes('\n')
+		if len(first) == 0 && err == io.EOF {
+			return nil
+		}
+		if strings.TrimRight(string(first), "\r\n") != formatHeader {
+			return s.migrate(io.MultiReader(bytes.NewReader(first), r))
+		}
+		if err != nil {
+			return nil
+		}
+		s.offset = int64(len(first))
+	}
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			// A line without its newline is still being written.
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		s.offset += int64(len(line))
+		s.fileRecords++
+		var rec record
+		if json.Unmarshal(line, &rec) != nil || rec.Command == "" {
 			continue
 		}
-		s.Add(line)
+		s.append(Entry{
+			Command:  rec.Command,
+			Time:     rec.Time,
+			Dir:      rec.Dir,
+			ExitCode: rec.ExitCode,
+			Duration: time.Duration(rec.DurationMS) * time.Millisecond,
+			Session:  rec.Session,
+		})
 	}
-	return scanner.Err()
 }
 
-func (s *Store) Add(cmd string) {
-	if cmd == "" {
-		return
+// migrate converts a plain-text history and rewrites it in the current
+// format. Use counts and times from the old usage sidecar are kept by
+// repeating each command count times at its last use.
+func (s *Store) migrate(r io.Reader) error {
+	var commands []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			commands = append(commands, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	usage, err := loadLegacyUsage(s.path + ".usage")
+	if err != nil {
+		return err
+	}
+	s.records = nil
+	for _, cmd := range commands {
+		u, ok := usage[cmd]
+		if !ok {
+			u = Usage{Count: 1}
+		}
+		for i := 0; i < u.Count; i++ {
+			s.append(Entry{Command: cmd, Time: u.Last})
+		}
 	}
-	if _, ok := s.seen[cmd]; ok {
-		return
+	if err := s.compact(); err != nil {
+		return err
 	}
-	s.entries = append(s.entries, cmd)
-	s.seen[cmd] = struct{}{}
-	if len(s.entries) > s.maxSize {
-		old := s.entries[0]
-		delete(s.seen, old)
-		s.entries = s.entries[1:]
+	if err := os.Remove(s.path + ".usage"); err != nil && !os.IsNotExist(err) {
+		return err
 	}
+	return nil
 }
 
-func (s *Store) Entries() []string {
-	out := make([]string, len(s.entries))
-	copy(out, s.entries)
-	return out
+func (s *Store) appendToFile(e Entry) error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if s.offset == 0 {
+		buf.WriteString(formatHeader + "\n")
+	}
+	if err := writeRecord(&buf, e); err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if s.file, err = f.Stat(); err != nil {
+		return err
+	}
+	s.offset += int64(buf.Len())
+	s.fileRecords++
+	return nil
 }
 
-func (s *Store) Save() error {
-	f, err := os.Create(s.path)
+// compactThreshold is how many records the file may hold before it is
+// rewritten, leaving headroom so compaction does not run on every command.
+func (s *Store) compactThreshold() int {
+	return s.maxSize + s.maxSize/10 + 1
+}
+
+// compact replaces the history file with the in-memory records through a
+// temporary file, so readers never see a partially written history.
+func (s *Store) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	w := bufio.NewWriter(f)
-	for _, line := range s.entries {
-		if _, err := w.WriteString(line + "\n"); err != nil {
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	w.WriteString(formatHeader + "\n")
+	for _, e := range s.records {
+		if err := writeRecord(w, e); err != nil {
+			tmp.Close()
 			return err
 		}
 	}
-	return w.Flush()
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	s.file, s.offset, s.fileRecords = fi, fi.Size(), len(s.records)
+	return nil
+}
+
+func writeRecord(w io.Writer, e Entry) error {
+	data, err := json.Marshal(record{
+		Command:    e.Command,
+		Time:       e.Time,
+		Dir:        e.Dir,
+		ExitCode:   e.ExitCode,
+		DurationMS: e.Duration.Milliseconds(),
+		Session:    e.Session,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// loadLegacyUsage reads the "count<TAB>unix-seconds<TAB>command" sidecar
+// written before records carried their own timestamps.
+func loadLegacyUsage(path string) (map[string]Usage, error) {
+	usage := map[string]Usage{}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return usage, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		count, err1 := strconv.Atoi(parts[0])
+		unix, err2 := strconv.ParseInt(parts[1], 10, 64)
+		if err1 != nil || err2 != nil || count < 1 {
+			continue
+		}
+		usage[parts[2]] = Usage{Command: parts[2], Count: count, Last: time.Unix(unix, 0)}
+	}
+	return usage, scanner.Err()
+}
+
+func newSessionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
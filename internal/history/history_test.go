@@ -1,8 +1,14 @@
 package history
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestStoreDedupAndMax(t *testing.T) {
@@ -24,3 +30,180 @@ func TestStoreDedupAndMax(t *testing.T) {
 		t.Fatalf("unexpected entries: %#v", entries)
 	}
 }
+
+func TestStoreRecordsPersistAndReorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	s, err := New(path, 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	first := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s.Record(Entry{Command: "go test ./...", Time: first, Dir: "/src/app", ExitCode: 1, Duration: 1500 * time.Millisecond})
+	s.Record(Entry{Command: "ls", Time: first.Add(time.Minute), Dir: "/src"})
+	s.Record(Entry{Command: "go test ./...", Time: first.Add(time.Hour), Dir: "/src/app"})
+	if err := s.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	reloaded, err := New(path, 10)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.Entries(); !reflect.DeepEqual(got, []string{"ls", "go test ./..."}) {
+		t.Fatalf("expected re-run command to move last, got %#v", got)
+	}
+	records := reloaded.Records()
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %#v", records)
+	}
+	want := Entry{Command: "go test ./...", Time: first, Dir: "/src/app", ExitCode: 1, Duration: 1500 * time.Millisecond, Session: s.Session()}
+	if !records[0].Time.Equal(want.Time) {
+		t.Fatalf("unexpected time %v", records[0].Time)
+	}
+	records[0].Time = want.Time
+	if records[0] != want {
+		t.Fatalf("unexpected record %#v", records[0])
+	}
+	usage := reloaded.Usage()
+	if usage[1].Count != 2 || !usage[1].Last.Equal(first.Add(time.Hour)) {
+		t.Fatalf("unexpected usage %#v", usage[1])
+	}
+}
+
+func TestStoreMigratesPlainTextHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("git status\n\nmake build\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(path+".usage", []byte("3\t1700000000\tmake build\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	s, err := New(path, 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	usage := s.Usage()
+	if len(usage) != 2 || usage[0].Command != "git status" || usage[1].Count != 3 || usage[1].Last.Unix() != 1700000000 {
+		t.Fatalf("unexpected migrated usage %#v", usage)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.HasPrefix(string(data), formatHeader+"\n") {
+		t.Fatalf("expected versioned file, got %q", data)
+	}
+	if _, err := os.Stat(path + ".usage"); !os.IsNotExist(err) {
+		t.Fatalf("expected usage sidecar to be removed, got %v", err)
+	}
+}
+
+func TestStoresShareAppendOnlyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	a, err := New(path, 100)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	b, err := New(path, 100)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if err := a.Add("echo from a"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := b.Add("echo from b"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	if got := a.Entries(); !reflect.DeepEqual(got, []string{"echo from a"}) {
+		t.Fatalf("expected other session's entry only after sync, got %#v", got)
+	}
+	if err := a.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := a.Entries(); !reflect.DeepEqual(got, []string{"echo from a", "echo from b"}) {
+		t.Fatalf("unexpected entries after sync: %#v", got)
+	}
+
+	// Exiting in either order keeps both sessions' commands.
+	if err := b.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := a.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	fresh, err := New(path, 100)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := fresh.Entries(); !reflect.DeepEqual(got, []string{"echo from a", "echo from b"}) {
+		t.Fatalf("unexpected entries after reload: %#v", got)
+	}
+}
+
+func TestStoreConcurrentAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	const sessions, perSession = 4, 25
+	var wg sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		s, err := New(path, 1000)
+		if err != nil {
+			t.Fatalf("new store: %v", err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < perSession; j++ {
+				if err := s.Add(fmt.Sprintf("cmd %d-%d", i, j)); err != nil {
+					t.Errorf("add: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s, err := New(path, 1000)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := len(s.Records()); got != sessions*perSession {
+		t.Fatalf("expected %d records, got %d", sessions*perSession, got)
+	}
+}
+
+func TestStoreCompactsToMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	a, err := New(path, 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	b, err := New(path, 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	for i := 0; i < 25; i++ {
+		if err := a.Add(fmt.Sprintf("cmd %d", i)); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n") - 1; lines > a.compactThreshold() {
+		t.Fatalf("expected compaction to keep the file near max size, got %d records", lines)
+	}
+
+	if err := b.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	records := b.Records()
+	if len(records) != 10 || records[9].Command != "cmd 24" || records[0].Command != "cmd 15" {
+		t.Fatalf("expected last 10 records after compaction, got %#v", records)
+	}
+}
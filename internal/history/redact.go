@@ -0,0 +1,94 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	RedactMask = "mask"
+	RedactDrop = "drop"
+
+	redactedValue = "****"
+)
+
+// builtinDetectors match common credential formats. Where a rule has a
+// "secret" group only that part is masked, so the surrounding command stays
+// readable.
+var builtinDetectors = []string{
+	// Key=value assignments and exports of secret-looking variables.
+	`(?i)\b[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIALS?)[A-Z0-9_]*\s*=\s*(?P<secret>"[^"]*"|'[^']*'|[^\s;&|]+)`,
+	// Authorization headers.
+	`(?i)authorization:\s*(?:bearer|basic|token)\s+(?P<secret>[^\s"']+)`,
+	// Password and token flags.
+	`(?i)--?(?:password|passwd|token|secret|api-key|access-key)(?:=|\s+)(?P<secret>"[^"]*"|'[^']*'|[^\s"']+)`,
+	// Credentials embedded in URLs.
+	`://[^/\s:@]+:(?P<secret>[^@\s/]+)@`,
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+	`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,
+	`\bglpat-[A-Za-z0-9_-]{20,}\b`,
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`,
+	`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`,
+	`\bAIza[0-9A-Za-z_-]{35}\b`,
+	`\bsk-[A-Za-z0-9_-]{20,}\b`,
+	`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----`,
+}
+
+// Redactor hides secrets in commands before they are stored.
+type Redactor struct {
+	mode  string
+	rules []*regexp.Regexp
+}
+
+// NewRedactor compiles patterns, plus the built-in detectors when builtin is
+// set. mode is RedactMask or RedactDrop.
+func NewRedactor(patterns []string, mode string, builtin bool) (*Redactor, error) {
+	if mode != RedactMask && mode != RedactDrop {
+		return nil, fmt.Errorf("unknown redact mode %q", mode)
+	}
+	r := &Redactor{mode: mode}
+	all := patterns
+	if builtin {
+		all = append(append([]string(nil), builtinDetectors...), patterns...)
+	}
+	for _, p := range all {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p, err)
+		}
+		r.rules = append(r.rules, re)
+	}
+	return r, nil
+}
+
+// Redact returns cmd with secrets masked. It reports false when cmd holds a
+// secret and the mode drops such commands.
+func (r *Redactor) Redact(cmd string) (string, bool) {
+	for _, re := range r.rules {
+		matches := re.FindAllStringSubmatchIndex(cmd, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		if r.mode == RedactDrop {
+			return "", false
+		}
+		group := re.SubexpIndex("secret")
+		var out strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if group > 0 && m[2*group] >= 0 {
+				start, end = m[2*group], m[2*group+1]
+			}
+			out.WriteString(cmd[last:start])
+			out.WriteString(redactedValue)
+			last = end
+		}
+		out.WriteString(cmd[last:])
+		cmd = out.String()
+	}
+	return cmd, true
+}
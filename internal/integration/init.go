@@ -33,12 +33,11 @@ if (($env:PATH -split ';' | Where-Object { $_ -ieq $__voidBinPath }).Count -eq 0
     $env:PATH = $__voidBinPath + ';' + $env:PATH
 }
 
-function __void_render_prompt([int]$code, [string]$workdir) {
+function __void_run([string]$arguments, [string]$fallback) {
     try {
         $psi = New-Object System.Diagnostics.ProcessStartInfo
         $psi.FileName = "void"
-        $escapedWorkdir = $workdir -replace '"', '\"'
-        $psi.Arguments = ('prompt --last-exit-code {0} --workdir "{1}"' -f $code, $escapedWorkdir)
+        $psi.Arguments = $arguments
         $psi.UseShellExecute = $false
         $psi.RedirectStandardOutput = $true
 
@@ -52,13 +51,39 @@ function __void_render_prompt([int]$code, [string]$workdir) {
 
         return [System.Text.Encoding]::UTF8.GetString($stdout.ToArray())
     } catch {
-        return "> "
+        return $fallback
+    }
+}
+
+function __void_render_prompt([int]$code, [string]$workdir, [long]$duration) {
+    $escapedWorkdir = $workdir -replace '"', '\"'
+    $columns = $Host.UI.RawUI.WindowSize.Width
+    return __void_run ('prompt --last-exit-code {0} --duration {1} --columns {2} --workdir "{3}"' -f $code, $duration, $columns, $escapedWorkdir) "> "
+}
+
+# With prompt.transient on, Enter redraws the prompt as a one-liner before
+# the command runs. Enter keeps its usual binding otherwise.
+$__voidTransient = (__void_run "config get prompt.transient" "").Trim() -eq "true"
+if ($__voidTransient -and (Get-Command Set-PSReadLineKeyHandler -ErrorAction SilentlyContinue)) {
+    Set-PSReadLineKeyHandler -Key Enter -ScriptBlock {
+        $global:__void_transient = $true
+        [Microsoft.PowerShell.PSConsoleReadLine]::InvokePrompt()
+        [Microsoft.PowerShell.PSConsoleReadLine]::AcceptLine()
     }
 }
 
 $global:__void_last_exit = 0
+$global:__void_last_history_id = 0
+$global:__void_transient = $false
+$global:__void_last_prompt = "> "
 function prompt {
     $lastCommandSucceeded = $?
+    if ($global:__void_transient) {
+        $global:__void_transient = $false
+        $transient = __void_run "prompt --transient" ""
+        if ($transient) { return $transient }
+        return $global:__void_last_prompt
+    }
     $code = $global:LASTEXITCODE
     if ($null -eq $code) { $code = 0 }
     if (-not $lastCommandSucceeded -and $code -eq 0) { $code = 1 }
@@ -76,23 +101,82 @@ function prompt {
         $env:VOID_LAST_ERROR = ""
     }
     $global:__void_last_exit = $code
-    __void_render_prompt -code $code -workdir $PWD.Path
+    $duration = 0
+    $lastCommand = Get-History -Count 1
+    if ($null -ne $lastCommand -and $lastCommand.Id -ne $global:__void_last_history_id) {
+        $global:__void_last_history_id = $lastCommand.Id
+        $duration = [long]($lastCommand.EndExecutionTime - $lastCommand.StartExecutionTime).TotalMilliseconds
+    }
+    $global:__void_last_prompt = __void_render_prompt -code $code -workdir $PWD.Path -duration $duration
+    return $global:__void_last_prompt
 }`
 }
 
 func bashScript() string {
-	return `__void_prompt() {
+	return `__void_now() {
+  if [ -n "${EPOCHREALTIME:-}" ]; then
+    local t="${EPOCHREALTIME/[.,]/}"
+    __void_ms=$(( 10#$t / 1000 ))
+  else
+    __void_ms=$(( SECONDS * 1000 ))
+  fi
+}
+__void_preexec() {
+  if [ -z "${__void_start:-}" ]; then
+    __void_now
+    __void_start=$__void_ms
+  fi
+}
+__void_prompt() {
   local code="$?"
-  PS1="$(void prompt --last-exit-code "$code" --workdir "$PWD")"
+  local duration=0
+  if [ -n "${__void_start:-}" ]; then
+    __void_now
+    duration=$(( __void_ms - __void_start ))
+  fi
+  PS1="$(void prompt --last-exit-code "$code" --duration "$duration" --columns "${COLUMNS:-0}" --workdir "$PWD")"
+  unset __void_start
 }
+# Keep an existing DEBUG trap (bash-preexec, direnv, ...) running first.
+__void_debug_trap="$(trap -p DEBUG)"
+if [[ $__void_debug_trap != *__void_preexec* ]]; then
+  __void_debug_trap="${__void_debug_trap:+$(eval "set -- $__void_debug_trap"; printf '%s' "$3")}"
+  trap -- "${__void_debug_trap:+$__void_debug_trap
+}__void_preexec" DEBUG
+fi
+unset __void_debug_trap
 PROMPT_COMMAND=__void_prompt`
 }
 
 func zshScript() string {
-	return `function precmd() {
+	return `zmodload zsh/datetime
+autoload -Uz add-zsh-hook
+function __void_preexec() {
+  __void_start=$EPOCHREALTIME
+}
+function __void_precmd() {
   local code="$?"
-  PROMPT="$(void prompt --last-exit-code "$code" --workdir "$PWD")"
-}`
+  local -i duration=0
+  if [[ -n $__void_start ]]; then
+    (( duration = (EPOCHREALTIME - __void_start) * 1000 ))
+    unset __void_start
+  fi
+  PROMPT="$(void prompt --last-exit-code "$code" --duration "$duration" --columns "$COLUMNS" --workdir "$PWD")"
+}
+add-zsh-hook preexec __void_preexec
+add-zsh-hook precmd __void_precmd
+if [[ "$(void config get prompt.transient 2>/dev/null)" == true ]]; then
+  function __void_transient() {
+    local line
+    line="$(void prompt --transient)"
+    if [[ -n $line ]]; then
+      PROMPT="$line"
+      zle reset-prompt
+    fi
+  }
+  autoload -Uz add-zle-hook-widget
+  add-zle-hook-widget line-finish __void_transient
+fi`
 }
 
 func cmdScript() string {
@@ -53,3 +53,56 @@ func TestPowershellInitScriptSetsUTF8Encoding(t *testing.T) {
 		}
 	}
 }
+
+func TestInitScriptsPassCommandDuration(t *testing.T) {
+	for _, shell := range []string{"powershell", "bash", "zsh"} {
+		snippet, err := InitScript(shell)
+		if err != nil {
+			t.Fatalf("InitScript returned error: %v", err)
+		}
+		if !strings.Contains(snippet, "--duration") {
+			t.Fatalf("expected %s snippet to pass --duration", shell)
+		}
+	}
+}
+
+func TestInitScriptsSupportRightAndTransientPrompts(t *testing.T) {
+	for shell, checks := range map[string][]string{
+		"powershell": {"--columns {2}", "prompt --transient", "if ($__voidTransient -and"},
+		"bash":       {"--columns"},
+		"zsh":        {"--columns", "prompt --transient", "add-zle-hook-widget line-finish __void_transient"},
+	} {
+		snippet, err := InitScript(shell)
+		if err != nil {
+			t.Fatalf("InitScript returned error: %v", err)
+		}
+		for _, check := range checks {
+			if !strings.Contains(snippet, check) {
+				t.Fatalf("expected %s snippet to contain %q", shell, check)
+			}
+		}
+	}
+}
+
+func TestInitScriptsKeepExistingHooks(t *testing.T) {
+	for shell, checks := range map[string][]string{
+		"bash": {"trap -p DEBUG"},
+		"zsh":  {"add-zsh-hook preexec __void_preexec", "add-zsh-hook precmd __void_precmd"},
+	} {
+		snippet, err := InitScript(shell)
+		if err != nil {
+			t.Fatalf("InitScript returned error: %v", err)
+		}
+		for _, check := range checks {
+			if !strings.Contains(snippet, check) {
+				t.Fatalf("expected %s snippet to contain %q", shell, check)
+			}
+		}
+	}
+	zsh, _ := InitScript("zsh")
+	for _, replaced := range []string{"function preexec()", "function precmd()", "zle -N zle-line-finish"} {
+		if strings.Contains(zsh, replaced) {
+			t.Fatalf("expected zsh snippet not to replace the user's hooks, found %q", replaced)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package lineedit
+
+import "unicode"
+
+// buffer is the editable input line. pos is a rune index in [0, len(text)].
+type buffer struct {
+	text []rune
+	pos  int
+}
+
+func (b *buffer) String() string {
+	return string(b.text)
+}
+
+func (b *buffer) set(s string) {
+	b.text = []rune(s)
+	b.pos = len(b.text)
+}
+
+func (b *buffer) insert(rs ...rune) {
+	text := make([]rune, 0, len(b.text)+len(rs))
+	text = append(text, b.text[:b.pos]...)
+	text = append(text, rs...)
+	text = append(text, b.text[b.pos:]...)
+	b.text = text
+	b.pos += len(rs)
+}
+
+// cut removes text[from:to] and returns it.
+func (b *buffer) cut(from, to int) string {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(b.text) {
+		to = len(b.text)
+	}
+	if from >= to {
+		return ""
+	}
+	removed := string(b.text[from:to])
+	b.text = append(b.text[:from:from], b.text[to:]...)
+	if b.pos > to {
+		b.pos -= to - from
+	} else if b.pos > from {
+		b.pos = from
+	}
+	return removed
+}
+
+func (b *buffer) backspace() {
+	if b.pos > 0 {
+		b.cut(b.pos-1, b.pos)
+	}
+}
+
+func (b *buffer) deleteChar() {
+	b.cut(b.pos, b.pos+1)
+}
+
+func (b *buffer) left() {
+	if b.pos > 0 {
+		b.pos--
+	}
+}
+
+func (b *buffer) right() {
+	if b.pos < len(b.text) {
+		b.pos++
+	}
+}
+
+func (b *buffer) home() {
+	b.pos = 0
+}
+
+func (b *buffer) end() {
+	b.pos = len(b.text)
+}
+
+// wordStart returns the start of the word before the cursor. With alnum set,
+// words are runs of letters and digits (Alt+B, Alt+Backspace); otherwise they
+// are whitespace-delimited (Ctrl+W), matching readline.
+func (b *buffer) wordStart(alnum bool) int {
+	isWord := wordPredicate(alnum)
+	i := b.pos
+	for i > 0 && !isWord(b.text[i-1]) {
+		i--
+	}
+	for i > 0 && isWord(b.text[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the end of the word after the cursor.
+func (b *buffer) wordEnd(alnum bool) int {
+	isWord := wordPredicate(alnum)
+	i := b.pos
+	for i < len(b.text) && !isWord(b.text[i]) {
+		i++
+	}
+	for i < len(b.text) && isWord(b.text[i]) {
+		i++
+	}
+	return i
+}
+
+func wordPredicate(alnum bool) func(rune) bool {
+	if alnum {
+		return func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	}
+	return func(r rune) bool { return !unicode.IsSpace(r) }
+}
+
+func (b *buffer) transpose() {
+	if len(b.text) < 2 || b.pos == 0 {
+		return
+	}
+	i := b.pos
+	if i == len(b.text) {
+		i--
+	}
+	b.text[i-1], b.text[i] = b.text[i], b.text[i-1]
+	b.pos = i + 1
+}
@@ -0,0 +1,343 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl+C.
+var ErrInterrupted = errors.New("interrupted")
+
+const defaultColumns = 80
+
+// Editor reads lines from a terminal with readline-style editing. When the
+// input is not a terminal it falls back to plain line reads.
+type Editor struct {
+	// Complete, when set, is consulted on Tab.
+	Complete Completer
+	// Search, when set, backs incremental history search on Ctrl+R.
+	Search Searcher
+	// Transient, when set, replaces the prompt and its header lines once a
+	// line is accepted, so scrollback keeps one short line per command.
+	Transient string
+
+	in      *os.File
+	out     io.Writer
+	reader  *bufio.Reader
+	columns func() int
+	kill    string
+}
+
+func New(in *os.File, out io.Writer) *Editor {
+	e := &Editor{in: in, out: out, reader: bufio.NewReader(in)}
+	e.columns = func() int {
+		if f, ok := out.(*os.File); ok {
+			if w, _, err := term.GetSize(int(f.Fd())); err == nil && w > 0 {
+				return w
+			}
+		}
+		return defaultColumns
+	}
+	return e
+}
+
+// ReadLine renders prompt and returns the edited line. history is ordered
+// oldest first and is browsed with Up/Down. It returns io.EOF on Ctrl+D at an
+// empty line and ErrInterrupted on Ctrl+C.
+func (e *Editor) ReadLine(prompt string, history []string) (string, error) {
+	fd := int(e.in.Fd())
+	if !term.IsTerminal(fd) {
+		return e.readCooked(prompt)
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return e.readCooked(prompt)
+	}
+	defer term.Restore(fd, state)
+	return e.edit(prompt, history)
+}
+
+func (e *Editor) readCooked(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	line, err := e.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+type session struct {
+	e         *Editor
+	header    string
+	prompt    string
+	buf       buffer
+	history   []string
+	histIdx   int
+	cursorRow int
+	lastKill  bool
+	menu      *menu
+	search    *search
+}
+
+func (e *Editor) edit(prompt string, history []string) (string, error) {
+	s := &session{e: e}
+	if idx := strings.LastIndex(prompt, "\n"); idx != -1 {
+		s.header = prompt[:idx+1]
+		s.prompt = prompt[idx+1:]
+	} else {
+		s.prompt = prompt
+	}
+	s.history = append(append([]string(nil), history...), "")
+	s.histIdx = len(s.history) - 1
+
+	s.writeHeader()
+	s.refresh()
+	for {
+		k, err := readKey(e.reader)
+		if err != nil {
+			if err == io.EOF && len(s.buf.text) == 0 {
+				s.finish()
+				return "", io.EOF
+			}
+			s.finish()
+			return s.buf.String(), err
+		}
+
+		if s.menu != nil && s.handleMenuKey(k) {
+			s.refresh()
+			continue
+		}
+		if s.search != nil && s.handleSearchKey(k) {
+			s.refresh()
+			continue
+		}
+
+		killed := false
+		switch k.code {
+		case keyEnter:
+			if e.Transient != "" {
+				s.collapse(e.Transient)
+			} else {
+				s.finish()
+			}
+			return s.buf.String(), nil
+		case keyRune:
+			s.buf.insert(k.r)
+		case keyTab:
+			s.complete()
+		case keyBackspace:
+			s.buf.backspace()
+		case keyDelete:
+			s.buf.deleteChar()
+		case keyLeft:
+			s.buf.left()
+		case keyRight:
+			s.buf.right()
+		case keyHome:
+			s.buf.home()
+		case keyEnd:
+			s.buf.end()
+		case keyWordLeft:
+			s.buf.pos = s.buf.wordStart(true)
+		case keyWordRight:
+			s.buf.pos = s.buf.wordEnd(true)
+		case keyUp:
+			s.historyMove(-1)
+		case keyDown:
+			s.historyMove(1)
+		case keyAltBackspace:
+			s.killRange(s.buf.wordStart(true), s.buf.pos, true)
+			killed = true
+		case keyAlt:
+			switch k.r {
+			case 'b':
+				s.buf.pos = s.buf.wordStart(true)
+			case 'f':
+				s.buf.pos = s.buf.wordEnd(true)
+			case 'd':
+				s.killRange(s.buf.pos, s.buf.wordEnd(true), false)
+				killed = true
+			}
+		case keyCtrl:
+			switch k.r {
+			case 'a':
+				s.buf.home()
+			case 'e':
+				s.buf.end()
+			case 'b':
+				s.buf.left()
+			case 'f':
+				s.buf.right()
+			case 'd':
+				if len(s.buf.text) == 0 {
+					s.finish()
+					return "", io.EOF
+				}
+				s.buf.deleteChar()
+			case 'k':
+				s.killRange(s.buf.pos, len(s.buf.text), false)
+				killed = true
+			case 'u':
+				s.killRange(0, s.buf.pos, true)
+				killed = true
+			case 'w':
+				s.killRange(s.buf.wordStart(false), s.buf.pos, true)
+				killed = true
+			case 'y':
+				s.buf.insert([]rune(e.kill)...)
+			case 't':
+				s.buf.transpose()
+			case 'p':
+				s.historyMove(-1)
+			case 'n':
+				s.historyMove(1)
+			case 'r':
+				s.startSearch()
+			case 'l':
+				s.write("\x1b[H\x1b[2J")
+				s.cursorRow = 0
+				s.writeHeader()
+			case 'c':
+				s.buf.end()
+				s.refresh()
+				s.write("^C\r\n")
+				return "", ErrInterrupted
+			}
+		}
+		s.lastKill = killed
+		s.refresh()
+	}
+}
+
+func (s *session) killRange(from, to int, backward bool) {
+	text := s.buf.cut(from, to)
+	switch {
+	case !s.lastKill:
+		s.e.kill = text
+	case backward:
+		s.e.kill = text + s.e.kill
+	default:
+		s.e.kill += text
+	}
+}
+
+func (s *session) historyMove(delta int) {
+	next := s.histIdx + delta
+	if next < 0 || next >= len(s.history) {
+		return
+	}
+	s.history[s.histIdx] = s.buf.String()
+	s.histIdx = next
+	s.buf.set(s.history[next])
+}
+
+func (s *session) write(text string) {
+	io.WriteString(s.e.out, text)
+}
+
+func (s *session) writeHeader() {
+	s.write(strings.ReplaceAll(s.header, "\n", "\r\n"))
+}
+
+// refresh redraws the prompt line, the input, which may wrap over several
+// terminal rows, and the completion menu or search status below it, and
+// leaves the cursor at the editing position.
+func (s *session) refresh() {
+	cols := s.e.columns()
+	if cols <= 0 {
+		cols = defaultColumns
+	}
+	promptWidth := visibleWidth(s.prompt)
+
+	var out strings.Builder
+	if s.cursorRow > 0 {
+		fmt.Fprintf(&out, "\x1b[%dA", s.cursorRow)
+	}
+	out.WriteString("\r")
+	out.WriteString(s.prompt)
+	if s.search != nil {
+		out.WriteString(s.search.highlight(s.buf.text))
+	} else {
+		out.WriteString(string(s.buf.text))
+	}
+	out.WriteString("\x1b[J")
+
+	total := promptWidth + visibleWidth(string(s.buf.text))
+	if total > 0 && total%cols == 0 {
+		// The cursor is parked past the last column; force the wrap so row
+		// arithmetic below holds.
+		out.WriteString("\r\n")
+	}
+	endRow := total / cols
+
+	var lines []string
+	switch {
+	case s.menu != nil:
+		lines = s.menu.render(cols)
+	case s.search != nil:
+		lines = []string{s.search.status(cols)}
+	}
+	for _, line := range lines {
+		out.WriteString("\r\n")
+		out.WriteString(line)
+	}
+	endRow += len(lines)
+
+	cursor := promptWidth + visibleWidth(string(s.buf.text[:s.buf.pos]))
+	row, col := cursor/cols, cursor%cols
+	if endRow > row {
+		fmt.Fprintf(&out, "\x1b[%dA", endRow-row)
+	}
+	out.WriteString("\r")
+	if col > 0 {
+		fmt.Fprintf(&out, "\x1b[%dC", col)
+	}
+	s.cursorRow = row
+	s.write(out.String())
+}
+
+// finish moves the cursor below the input so command output starts on a
+// fresh line.
+func (s *session) finish() {
+	s.menu = nil
+	s.search = nil
+	s.buf.end()
+	s.refresh()
+	s.write("\r\n")
+}
+
+// collapse redraws the accepted line after transient in place of the
+// prompt and its header, then moves below it like finish.
+func (s *session) collapse(transient string) {
+	s.menu = nil
+	s.search = nil
+	s.buf.end()
+	s.refresh()
+	if up := s.cursorRow + s.headerRows(); up > 0 {
+		s.write(fmt.Sprintf("\x1b[%dA", up))
+	}
+	s.write("\r\x1b[J" + transient + string(s.buf.text) + "\r\n")
+}
+
+// headerRows counts the terminal rows taken by the header, including lines
+// that wrapped.
+func (s *session) headerRows() int {
+	if s.header == "" {
+		return 0
+	}
+	cols := s.e.columns()
+	if cols <= 0 {
+		cols = defaultColumns
+	}
+	rows := 0
+	for _, line := range strings.Split(strings.TrimSuffix(s.header, "\n"), "\n") {
+		rows += max(1, (visibleWidth(line)+cols-1)/cols)
+	}
+	return rows
+}
@@ -0,0 +1,249 @@
+package lineedit
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string) (*Editor, *bytes.Buffer) {
+	var out bytes.Buffer
+	return &Editor{
+		out:     &out,
+		reader:  bufio.NewReader(strings.NewReader(input)),
+		columns: func() int { return 80 },
+	}, &out
+}
+
+func TestEditCursorMovementAndInsert(t *testing.T) {
+	e, _ := newTestEditor("world\x01hello \x05!\r")
+	got, err := e.edit("> ", nil)
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if got != "hello world!" {
+		t.Fatalf("expected %q, got %q", "hello world!", got)
+	}
+}
+
+func TestEditWordMotionsAndKills(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "ctrl-w kills whitespace word", input: "git commit -m\x17\r", want: "git commit "},
+		{name: "ctrl-k kills to end", input: "echo one two\x1bb\x0b\r", want: "echo one "},
+		{name: "ctrl-u then yank", input: "echo hi\x15ls; \x19\r", want: "ls; echo hi"},
+		{name: "alt-b alt-d", input: "cp foo.txt bar\x1bb\x1bb\x1bd\r", want: "cp foo. bar"},
+		{name: "alt-backspace kills alnum word", input: "cd src/app\x1b\x7f\r", want: "cd src/"},
+		{name: "ctrl-left ctrl-right", input: "a b c\x1b[1;5D\x1b[1;5DX\x1b[1;5CY\r", want: "a XbY c"},
+		{name: "delete and backspace", input: "abcd\x1b[D\x1b[D\x1b[3~\x7f\r", want: "ad"},
+		{name: "transpose", input: "sl\x14\r", want: "ls"},
+		{name: "consecutive kills accumulate", input: "one two three\x17\x17\x19\x19\r", want: "one two threetwo three"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, _ := newTestEditor(tc.input)
+			got, err := e.edit("> ", nil)
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEditHistoryRecall(t *testing.T) {
+	history := []string{"echo first", "echo second"}
+
+	e, _ := newTestEditor("\x1b[A\x1b[A\r")
+	if got, _ := e.edit("> ", history); got != "echo first" {
+		t.Fatalf("expected oldest entry after two ups, got %q", got)
+	}
+
+	e, _ = newTestEditor("draft\x1b[A\x1b[B\r")
+	if got, _ := e.edit("> ", history); got != "draft" {
+		t.Fatalf("expected draft to be restored after down, got %q", got)
+	}
+
+	e, _ = newTestEditor("\x10 --x\x10\x0e\r")
+	if got, _ := e.edit("> ", history); got != "echo second --x" {
+		t.Fatalf("expected edited recall to be kept, got %q", got)
+	}
+}
+
+func TestEditCtrlDAndCtrlC(t *testing.T) {
+	e, _ := newTestEditor("\x04")
+	if _, err := e.edit("> ", nil); err != io.EOF {
+		t.Fatalf("expected io.EOF on ctrl-d at empty line, got %v", err)
+	}
+
+	e, _ = newTestEditor("ab\x01\x04\r")
+	if got, err := e.edit("> ", nil); err != nil || got != "b" {
+		t.Fatalf("expected ctrl-d to delete char, got %q err=%v", got, err)
+	}
+
+	e, _ = newTestEditor("abc\x03")
+	if _, err := e.edit("> ", nil); err != ErrInterrupted {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+}
+
+func TestEditRedrawsOnlyLastPromptLine(t *testing.T) {
+	e, out := newTestEditor("x\r")
+	if _, err := e.edit("badges\n│ > ", nil); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	got := out.String()
+	if strings.Count(got, "badges") != 1 {
+		t.Fatalf("expected badge line to be written once, got %q", got)
+	}
+	if !strings.HasPrefix(got, "badges\r\n") {
+		t.Fatalf("expected raw-mode newline after badges, got %q", got)
+	}
+	if !strings.Contains(got, "\r│ > x") {
+		t.Fatalf("expected prompt line to be redrawn with input, got %q", got)
+	}
+}
+
+func TestEditCollapsesToTransientPrompt(t *testing.T) {
+	e, out := newTestEditor("ls\r")
+	e.Transient = "> "
+	if _, err := e.edit("badges\n│ > ", nil); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	got := out.String()
+	if !strings.HasSuffix(got, "\x1b[1A\r\x1b[J> ls\r\n") {
+		t.Fatalf("expected the prompt to collapse into one line, got %q", got)
+	}
+}
+
+func TestVisibleWidthSkipsEscapesAndCountsWideRunes(t *testing.T) {
+	if got := visibleWidth("\x1b[1m\x1b[38;2;1;2;3m> \x1b[0m"); got != 2 {
+		t.Fatalf("expected escapes to be skipped, got %d", got)
+	}
+	if got := visibleWidth("界📂"); got != 4 {
+		t.Fatalf("expected wide runes to take two columns, got %d", got)
+	}
+}
+
+func staticCompleter(values ...string) Completer {
+	return func(line string, pos int) Completion {
+		runes := []rune(line)
+		start := pos
+		for start > 0 && runes[start-1] != ' ' {
+			start--
+		}
+		word := string(runes[start:pos])
+		var out []Candidate
+		for _, v := range values {
+			if strings.HasPrefix(v, word) {
+				out = append(out, Candidate{Value: v, Description: "desc " + v})
+			}
+		}
+		return Completion{Candidates: out, Start: start, End: pos}
+	}
+}
+
+func TestEditTabCompletion(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "single candidate is inserted", input: "gi\t\r", want: "git "},
+		{name: "common prefix is extended", input: "do\t\r", want: "docker"},
+		{name: "menu tab and enter accepts", input: "docker\t\t\r\r", want: "docker-compose "},
+		{name: "menu arrows select", input: "docker\t\x1b[C\x1b[D\x1b[C\r\r", want: "docker-compose "},
+		{name: "ctrl-g closes menu", input: "docker\t\x07\r", want: "docker"},
+		{name: "typing closes menu", input: "docker\tx\r", want: "dockerx"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, _ := newTestEditor(tc.input)
+			e.Complete = staticCompleter("git", "docker", "docker-compose")
+			got, err := e.edit("> ", nil)
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMenuRenderWrapsAcrossColumns(t *testing.T) {
+	m := &menu{completion: Completion{Candidates: []Candidate{
+		{Value: "alpha"}, {Value: "beta"}, {Value: "gamma"}, {Value: "delta"}, {Value: "omega"},
+	}}, selected: 3}
+
+	lines := m.render(16)
+	if m.columns != 2 {
+		t.Fatalf("expected 2 columns in 16 cells, got %d", m.columns)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 rows, got %d: %q", len(lines), lines)
+	}
+	if !strings.Contains(lines[1], "\x1b[7mdelta") {
+		t.Fatalf("expected selected candidate to be highlighted, got %q", lines[1])
+	}
+
+	m.selected = 0
+	m.completion.Candidates[0].Description = "first letter"
+	for _, line := range m.render(40) {
+		if !strings.Contains(line, "first letter") && strings.Contains(line, "alpha") {
+			t.Fatalf("expected description next to candidate, got %q", line)
+		}
+	}
+}
+
+func TestEditReverseSearch(t *testing.T) {
+	history := []string{"go test ./...", "git status", "go vet ./..."}
+	searcher := func(query string) []SearchResult {
+		var out []SearchResult
+		for i := len(history) - 1; i >= 0; i-- {
+			if idx := strings.Index(history[i], query); idx != -1 {
+				positions := make([]int, 0, len(query))
+				for p := idx; p < idx+len(query); p++ {
+					positions = append(positions, p)
+				}
+				out = append(out, SearchResult{Text: history[i], Positions: positions})
+			}
+		}
+		return out
+	}
+
+	e, out := newTestEditor("\x12go\x12\r")
+	e.Search = searcher
+	got, err := e.edit("> ", history)
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if got != "go test ./..." {
+		t.Fatalf("expected second match after Ctrl+R cycle, got %q", got)
+	}
+	if !strings.Contains(out.String(), "\x1b[1;4mg\x1b[0m\x1b[1;4mo\x1b[0m vet") {
+		t.Fatalf("expected highlighted match in output: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "2/2") {
+		t.Fatalf("expected match counter in output: %q", out.String())
+	}
+
+	e, _ = newTestEditor("draft\x12git\x1b[D!\r")
+	e.Search = searcher
+	if got, _ := e.edit("> ", history); got != "git statu!s" {
+		t.Fatalf("expected editing key to accept the match, got %q", got)
+	}
+
+	e, _ = newTestEditor("draft\x12git\x07\r")
+	e.Search = searcher
+	if got, _ := e.edit("> ", history); got != "draft" {
+		t.Fatalf("expected Ctrl+G to restore the line, got %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+package lineedit
+
+import (
+	"bufio"
+	"strings"
+)
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyCtrl
+	keyAlt
+	keyEnter
+	keyTab
+	keyShiftTab
+	keyBackspace
+	keyAltBackspace
+	keyDelete
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyWordLeft
+	keyWordRight
+	keyHome
+	keyEnd
+	keyEsc
+	keyUnknown
+)
+
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// readKey decodes a single keypress from a terminal in raw mode. Escape
+// sequences follow the xterm/VT conventions that Windows also emits once
+// virtual terminal input is enabled.
+func readKey(r *bufio.Reader) (key, error) {
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+
+	switch {
+	case ch == '\r' || ch == '\n':
+		return key{code: keyEnter}, nil
+	case ch == '\t':
+		return key{code: keyTab}, nil
+	case ch == 0x7f || ch == 0x08:
+		return key{code: keyBackspace}, nil
+	case ch == 0x1b:
+		return readEscape(r)
+	case ch > 0 && ch < 0x20:
+		return key{code: keyCtrl, r: 'a' + ch - 1}, nil
+	}
+	return key{code: keyRune, r: ch}, nil
+}
+
+func readEscape(r *bufio.Reader) (key, error) {
+	if r.Buffered() == 0 {
+		return key{code: keyEsc}, nil
+	}
+	next, _, err := r.ReadRune()
+	if err != nil {
+		return key{code: keyEsc}, nil
+	}
+
+	switch next {
+	case '[':
+		return readCSI(r)
+	case 'O':
+		final, _, err := r.ReadRune()
+		if err != nil {
+			return key{code: keyUnknown}, nil
+		}
+		return csiKey("", final), nil
+	case 0x7f, 0x08:
+		return key{code: keyAltBackspace}, nil
+	case 0x1b:
+		return key{code: keyEsc}, nil
+	}
+	return key{code: keyAlt, r: next}, nil
+}
+
+func readCSI(r *bufio.Reader) (key, error) {
+	var params strings.Builder
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return key{code: keyUnknown}, nil
+		}
+		if b >= 0x40 && b <= 0x7e {
+			return csiKey(params.String(), rune(b)), nil
+		}
+		params.WriteByte(b)
+	}
+}
+
+func csiKey(params string, final rune) key {
+	modifier := ""
+	if idx := strings.Index(params, ";"); idx != -1 {
+		modifier = params[idx+1:]
+		params = params[:idx]
+	}
+	// xterm encodes Alt as 3 and Ctrl as 5 in the modifier parameter.
+	wordMotion := modifier == "3" || modifier == "5"
+
+	switch final {
+	case 'A':
+		return key{code: keyUp}
+	case 'B':
+		return key{code: keyDown}
+	case 'C':
+		if wordMotion {
+			return key{code: keyWordRight}
+		}
+		return key{code: keyRight}
+	case 'D':
+		if wordMotion {
+			return key{code: keyWordLeft}
+		}
+		return key{code: keyLeft}
+	case 'H':
+		return key{code: keyHome}
+	case 'F':
+		return key{code: keyEnd}
+	case 'Z':
+		return key{code: keyShiftTab}
+	case '~':
+		switch params {
+		case "1", "7":
+			return key{code: keyHome}
+		case "4", "8":
+			return key{code: keyEnd}
+		case "3":
+			return key{code: keyDelete}
+		}
+	}
+	return key{code: keyUnknown}
+}
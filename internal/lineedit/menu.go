@@ -0,0 +1,230 @@
+package lineedit
+
+import (
+	"strings"
+)
+
+const (
+	maxMenuRows        = 10
+	maxDescriptionCols = 32
+	menuCellGap        = 2
+)
+
+// Candidate is one entry of the Tab completion menu. Partial candidates,
+// such as directories, are inserted without a trailing space so completion
+// can continue.
+type Candidate struct {
+	Value       string
+	Description string
+	Partial     bool
+}
+
+// Completion holds the candidates for the word under the cursor. Start and
+// End are rune offsets of the text an accepted candidate replaces.
+type Completion struct {
+	Candidates []Candidate
+	Start      int
+	End        int
+}
+
+// Completer returns completions for line with the cursor at rune offset pos.
+type Completer func(line string, pos int) Completion
+
+type menu struct {
+	completion Completion
+	selected   int
+	columns    int
+}
+
+// complete handles a Tab press. A single candidate is inserted directly; a
+// shared prefix is extended in place; otherwise the menu opens.
+func (s *session) complete() {
+	if s.e.Complete == nil {
+		return
+	}
+	c := s.e.Complete(s.buf.String(), s.buf.pos)
+	if len(c.Candidates) == 0 || c.Start < 0 || c.End > len(s.buf.text) || c.Start > c.End {
+		return
+	}
+	if len(c.Candidates) == 1 {
+		s.accept(c, c.Candidates[0].Value, !c.Candidates[0].Partial)
+		return
+	}
+
+	current := string(s.buf.text[c.Start:c.End])
+	if prefix := commonPrefix(c.Candidates); len([]rune(prefix)) > len([]rune(current)) {
+		s.accept(c, prefix, false)
+		return
+	}
+	s.menu = &menu{completion: c}
+}
+
+// accept replaces the completed word with value and closes the menu. A
+// space follows final values.
+func (s *session) accept(c Completion, value string, final bool) {
+	s.buf.cut(c.Start, c.End)
+	s.buf.pos = c.Start
+	s.buf.insert([]rune(value)...)
+	if final {
+		s.buf.insert(' ')
+	}
+	s.menu = nil
+}
+
+// handleMenuKey processes k while the menu is open. It reports false when
+// the key closed the menu and should be handled by the editor instead.
+func (s *session) handleMenuKey(k key) bool {
+	m := s.menu
+	n := len(m.completion.Candidates)
+	cols := m.columns
+	if cols < 1 {
+		cols = 1
+	}
+
+	switch k.code {
+	case keyTab, keyRight:
+		m.selected = (m.selected + 1) % n
+	case keyShiftTab, keyLeft:
+		m.selected = (m.selected - 1 + n) % n
+	case keyDown:
+		if m.selected+cols < n {
+			m.selected += cols
+		} else {
+			m.selected %= cols
+		}
+	case keyUp:
+		if m.selected-cols >= 0 {
+			m.selected -= cols
+		} else {
+			last := (n - 1) / cols * cols
+			m.selected = last + m.selected
+			if m.selected >= n {
+				m.selected -= cols
+			}
+		}
+	case keyEnter:
+		selected := m.completion.Candidates[m.selected]
+		s.accept(m.completion, selected.Value, !selected.Partial)
+	case keyEsc:
+		s.menu = nil
+	case keyCtrl:
+		if k.r != 'g' {
+			s.menu = nil
+			return false
+		}
+		s.menu = nil
+	default:
+		s.menu = nil
+		return false
+	}
+	return true
+}
+
+// render lays the candidates out row by row in as many columns as fit the
+// terminal width and returns at most maxMenuRows lines around the selection.
+func (m *menu) render(width int) []string {
+	candidates := m.completion.Candidates
+	valueWidth, descWidth := 0, 0
+	for _, c := range candidates {
+		if w := visibleWidth(c.Value); w > valueWidth {
+			valueWidth = w
+		}
+		if w := visibleWidth(c.Description); w > descWidth {
+			descWidth = w
+		}
+	}
+	if descWidth > maxDescriptionCols {
+		descWidth = maxDescriptionCols
+	}
+	cellWidth := valueWidth
+	if descWidth > 0 {
+		cellWidth += menuCellGap + descWidth
+	}
+	if cellWidth > width-1 {
+		cellWidth = width - 1
+	}
+	if cellWidth < 1 {
+		cellWidth = 1
+	}
+
+	m.columns = (width - 1 + menuCellGap) / (cellWidth + menuCellGap)
+	if m.columns < 1 {
+		m.columns = 1
+	}
+	rows := (len(candidates) + m.columns - 1) / m.columns
+	first := 0
+	if selRow := m.selected / m.columns; selRow >= maxMenuRows {
+		first = selRow - maxMenuRows + 1
+	}
+	last := first + maxMenuRows
+	if last > rows {
+		last = rows
+	}
+
+	lines := make([]string, 0, last-first)
+	for row := first; row < last; row++ {
+		var line strings.Builder
+		for col := 0; col < m.columns; col++ {
+			i := row*m.columns + col
+			if i >= len(candidates) {
+				break
+			}
+			if col > 0 {
+				line.WriteString(strings.Repeat(" ", menuCellGap))
+			}
+			line.WriteString(m.cell(candidates[i], i == m.selected, valueWidth, descWidth, cellWidth))
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+func (m *menu) cell(c Candidate, selected bool, valueWidth, descWidth, cellWidth int) string {
+	value := truncateWidth(c.Value, cellWidth)
+	text := value + strings.Repeat(" ", max(0, valueWidth-visibleWidth(value)))
+	desc := ""
+	if descWidth > 0 && cellWidth-valueWidth-menuCellGap > 0 {
+		desc = truncateWidth(c.Description, cellWidth-valueWidth-menuCellGap)
+		desc = strings.Repeat(" ", menuCellGap) + desc + strings.Repeat(" ", max(0, cellWidth-valueWidth-menuCellGap-visibleWidth(desc)))
+	}
+	text = truncateWidth(text, cellWidth)
+
+	if selected {
+		return "\x1b[7m" + text + desc + "\x1b[0m"
+	}
+	if desc == "" {
+		return text
+	}
+	return text + "\x1b[2m" + desc + "\x1b[0m"
+}
+
+func truncateWidth(s string, width int) string {
+	if visibleWidth(s) <= width {
+		return s
+	}
+	var out strings.Builder
+	used := 0
+	for _, r := range s {
+		w := runeWidth(r)
+		if used+w > width-1 {
+			break
+		}
+		out.WriteRune(r)
+		used += w
+	}
+	out.WriteString("…")
+	return out.String()
+}
+
+func commonPrefix(candidates []Candidate) string {
+	prefix := []rune(candidates[0].Value)
+	for _, c := range candidates[1:] {
+		value := []rune(c.Value)
+		n := 0
+		for n < len(prefix) && n < len(value) && prefix[n] == value[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
@@ -0,0 +1,168 @@
+package lineedit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const defaultRows = 24
+
+// Choice is one entry offered by Pick. Preview is drawn under the label and
+// may span several lines and carry ANSI colours.
+type Choice struct {
+	Label   string
+	Preview string
+}
+
+type picker struct {
+	e        *Editor
+	title    string
+	choices  []Choice
+	selected int
+	top      int
+	drawn    int
+	// height overrides the terminal height when set.
+	height int
+}
+
+// Pick lists choices under title and lets the user move through them with
+// Up/Down (or Ctrl+P/Ctrl+N, k/j). It returns the index chosen with Enter,
+// or -1 when the list is dismissed with Esc, q or Ctrl+C. The list is erased
+// before Pick returns.
+func (e *Editor) Pick(title string, choices []Choice, selected int) (int, error) {
+	fd := int(e.in.Fd())
+	if !term.IsTerminal(fd) {
+		return -1, errors.New("not a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return -1, err
+	}
+	defer term.Restore(fd, state)
+
+	if selected < 0 || selected >= len(choices) {
+		selected = 0
+	}
+	p := &picker{e: e, title: title, choices: choices, selected: selected}
+	// Long previews are clipped rather than wrapped so every entry keeps a
+	// fixed height.
+	p.write("\x1b[?25l\x1b[?7l")
+	defer p.write("\x1b[?7h\x1b[?25h")
+	return p.run()
+}
+
+func (p *picker) run() (int, error) {
+	p.draw()
+	for {
+		k, err := readKey(p.e.reader)
+		if err != nil {
+			p.clear()
+			return -1, err
+		}
+		switch {
+		case k.code == keyUp, k.code == keyShiftTab, k.code == keyCtrl && k.r == 'p', k.code == keyRune && k.r == 'k':
+			p.move(-1)
+		case k.code == keyDown, k.code == keyTab, k.code == keyCtrl && k.r == 'n', k.code == keyRune && k.r == 'j':
+			p.move(1)
+		case k.code == keyHome:
+			p.selected = 0
+		case k.code == keyEnd:
+			p.selected = len(p.choices) - 1
+		case k.code == keyEnter:
+			p.clear()
+			return p.selected, nil
+		case k.code == keyEsc, k.code == keyCtrl && (k.r == 'c' || k.r == 'g'), k.code == keyRune && k.r == 'q':
+			p.clear()
+			return -1, nil
+		}
+		p.draw()
+	}
+}
+
+func (p *picker) move(delta int) {
+	if len(p.choices) == 0 {
+		return
+	}
+	p.selected = (p.selected + delta + len(p.choices)) % len(p.choices)
+}
+
+func (p *picker) write(text string) {
+	fmt.Fprint(p.e.out, text)
+}
+
+func (p *picker) rows() int {
+	if p.height > 0 {
+		return p.height
+	}
+	if f, ok := p.e.out.(*os.File); ok {
+		if _, h, err := term.GetSize(int(f.Fd())); err == nil && h > 0 {
+			return h
+		}
+	}
+	return defaultRows
+}
+
+func (p *picker) entry(i int) []string {
+	c := p.choices[i]
+	label := "  " + c.Label
+	if i == p.selected {
+		label = "\x1b[7m❯ " + c.Label + "\x1b[0m"
+	}
+	lines := []string{label}
+	for _, line := range strings.Split(strings.TrimRight(c.Preview, "\n"), "\n") {
+		lines = append(lines, "    "+line+"\x1b[0m")
+	}
+	return lines
+}
+
+// render returns the title and as many entries as fit on screen, scrolled
+// so the selected entry is visible.
+func (p *picker) render() []string {
+	height := p.rows() - 1
+	if p.selected < p.top {
+		p.top = p.selected
+	}
+	for {
+		used := 1
+		last := p.top
+		for ; last < len(p.choices); last++ {
+			n := len(p.entry(last))
+			if used+n > height && last > p.top {
+				break
+			}
+			used += n
+		}
+		if p.selected < last || p.top == p.selected {
+			lines := []string{p.title}
+			for i := p.top; i < last; i++ {
+				lines = append(lines, p.entry(i)...)
+			}
+			return lines
+		}
+		p.top++
+	}
+}
+
+func (p *picker) draw() {
+	var out strings.Builder
+	if p.drawn > 1 {
+		fmt.Fprintf(&out, "\x1b[%dA", p.drawn-1)
+	}
+	out.WriteString("\r\x1b[J")
+	lines := p.render()
+	out.WriteString(strings.Join(lines, "\r\n"))
+	p.drawn = len(lines)
+	p.write(out.String())
+}
+
+func (p *picker) clear() {
+	if p.drawn > 1 {
+		p.write(fmt.Sprintf("\x1b[%dA", p.drawn-1))
+	}
+	p.write("\r\x1b[J")
+	p.drawn = 0
+}
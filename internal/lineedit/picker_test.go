@@ -0,0 +1,53 @@
+package lineedit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPickerMovesAndSelects(t *testing.T) {
+	choices := []Choice{{Label: "a"}, {Label: "b"}, {Label: "c"}}
+	cases := []struct {
+		name  string
+		input string
+		start int
+		want  int
+	}{
+		{name: "enter keeps initial", input: "\r", start: 1, want: 1},
+		{name: "down twice", input: "\x1b[B\x1b[B\r", start: 0, want: 2},
+		{name: "up wraps", input: "\x1b[A\r", start: 0, want: 2},
+		{name: "vi keys", input: "jjk\r", start: 0, want: 1},
+		{name: "escape cancels", input: "j\x1b", start: 0, want: -1},
+		{name: "q cancels", input: "q", start: 0, want: -1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, _ := newTestEditor(tc.input)
+			p := &picker{e: e, title: "pick", choices: choices, selected: tc.start, height: 24}
+			got, err := p.run()
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPickerScrollsToSelection(t *testing.T) {
+	var choices []Choice
+	for _, name := range []string{"one", "two", "three", "four", "five"} {
+		choices = append(choices, Choice{Label: name, Preview: name + " prompt"})
+	}
+	e, _ := newTestEditor("")
+	p := &picker{e: e, title: "pick", choices: choices, selected: 4, height: 6}
+	lines := p.render()
+	if len(lines) > 5 {
+		t.Fatalf("expected the list to fit 5 rows, got %d: %q", len(lines), lines)
+	}
+	joined := strings.Join(lines, "\n")
+	if !strings.Contains(joined, "❯ five") || strings.Contains(joined, "one") {
+		t.Fatalf("expected the list scrolled to the selection, got %q", lines)
+	}
+}
@@ -0,0 +1,123 @@
+package lineedit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchResult is a history entry found by a Searcher. Positions are the
+// rune offsets of the characters that matched the query.
+type SearchResult struct {
+	Text      string
+	Positions []int
+}
+
+// Searcher returns the history entries matching query, best first.
+type Searcher func(query string) []SearchResult
+
+type search struct {
+	query    []rune
+	results  []SearchResult
+	index    int
+	original string
+}
+
+// startSearch enters incremental history search on Ctrl+R. The line being
+// edited is restored if the search is cancelled.
+func (s *session) startSearch() {
+	if s.e.Search == nil {
+		return
+	}
+	s.menu = nil
+	s.search = &search{original: s.buf.String()}
+	s.runSearch()
+}
+
+func (s *session) runSearch() {
+	sr := s.search
+	sr.results = s.e.Search(string(sr.query))
+	sr.index = 0
+	s.showSearchResult()
+}
+
+func (s *session) showSearchResult() {
+	if r := s.search.current(); r != nil {
+		s.buf.set(r.Text)
+	}
+}
+
+func (sr *search) current() *SearchResult {
+	if sr.index < 0 || sr.index >= len(sr.results) {
+		return nil
+	}
+	return &sr.results[sr.index]
+}
+
+// handleSearchKey processes k during a search. Typing refines the query,
+// Ctrl+R and Ctrl+S step through the matches and Esc or Ctrl+G restore the
+// original line. Any other key keeps the shown match and reports false so
+// the editor handles it, which makes Enter run the match.
+func (s *session) handleSearchKey(k key) bool {
+	sr := s.search
+	switch {
+	case k.code == keyRune:
+		sr.query = append(sr.query, k.r)
+		s.runSearch()
+	case k.code == keyBackspace:
+		if len(sr.query) > 0 {
+			sr.query = sr.query[:len(sr.query)-1]
+			s.runSearch()
+		}
+	case k.code == keyCtrl && k.r == 'r':
+		if n := len(sr.results); n > 0 {
+			sr.index = (sr.index + 1) % n
+			s.showSearchResult()
+		}
+	case k.code == keyCtrl && k.r == 's':
+		if n := len(sr.results); n > 0 {
+			sr.index = (sr.index - 1 + n) % n
+			s.showSearchResult()
+		}
+	case k.code == keyEsc || (k.code == keyCtrl && k.r == 'g'):
+		s.buf.set(sr.original)
+		s.search = nil
+	default:
+		s.search = nil
+		return false
+	}
+	return true
+}
+
+// highlight renders text with the matched positions underlined in bold.
+func (sr *search) highlight(text []rune) string {
+	r := sr.current()
+	if r == nil || len(r.Positions) == 0 {
+		return string(text)
+	}
+	matched := make(map[int]bool, len(r.Positions))
+	for _, p := range r.Positions {
+		matched[p] = true
+	}
+	var out strings.Builder
+	for i, ch := range text {
+		if matched[i] {
+			out.WriteString("\x1b[1;4m")
+			out.WriteRune(ch)
+			out.WriteString("\x1b[0m")
+			continue
+		}
+		out.WriteRune(ch)
+	}
+	return out.String()
+}
+
+// status is the line shown below the input during a search, fitted to width.
+func (sr *search) status(width int) string {
+	const label = "(search) "
+	info := " no match"
+	if len(sr.results) > 0 {
+		info = fmt.Sprintf(" %d/%d", sr.index+1, len(sr.results))
+	}
+	query := truncateWidth(string(sr.query), max(1, width-1-len(label)-len(info)))
+	return "\x1b[2m" + label + "\x1b[0m" + query + "\x1b[2m" + info + "\x1b[0m"
+}
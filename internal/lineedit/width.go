@@ -0,0 +1,13 @@
+package lineedit
+
+import "github.com/void-shell/void/internal/textwidth"
+
+// visibleWidth returns the number of terminal columns s occupies, skipping
+// ANSI escape sequences.
+func visibleWidth(s string) int {
+	return textwidth.String(s)
+}
+
+func runeWidth(r rune) int {
+	return textwidth.Rune(r)
+}
@@ -0,0 +1,312 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSegmentTimeout = 200 * time.Millisecond
+	// firstRunTimeout bounds how long a one-shot prompt waits for a segment
+	// it has no up-to-date value for.
+	firstRunTimeout = 10 * time.Second
+	// maxSavedSegments caps the cache file; the oldest entries go first.
+	maxSavedSegments = 256
+)
+
+// segmentData is the raw state a slow segment computes. It is styled when
+// the prompt is drawn, so renderers with different options can share it.
+type segmentData struct {
+	Git     *gitStatus `json:"git,omitempty"`
+	Version string     `json:"version,omitempty"`
+}
+
+// segmentCache keeps the output of slow segments per directory so a prompt
+// never has to wait for the same work twice.
+//
+// With a file, the cache also outlives the process. That is how one-shot
+// `void prompt` runs from shell hooks work: their background work dies with
+// them, so a segment without a value for the current stamp is waited for
+// instead, and kept on disk for the next prompt.
+type segmentCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+	path    string
+	// outdated is set once a prompt was drawn with an old value because the
+	// new one was still being computed.
+	outdated bool
+}
+
+type cacheKey struct {
+	segment string
+	dir     string
+}
+
+type cacheEntry struct {
+	// latest is the newest computation, which may still be running.
+	latest *asyncResult
+	// done is the newest computation that has finished.
+	done *asyncResult
+}
+
+type asyncResult struct {
+	stamp    string
+	finished chan struct{}
+	value    segmentData
+	computed time.Time
+}
+
+// newSegmentCache returns an empty cache, or one loaded from path when path
+// is not empty.
+func newSegmentCache(path string) *segmentCache {
+	c := &segmentCache{entries: map[cacheKey]*cacheEntry{}, path: path}
+	if path != "" {
+		c.load()
+	}
+	return c
+}
+
+// pendingSegment is a slow segment requested for the current prompt.
+type pendingSegment struct {
+	c     *segmentCache
+	entry *cacheEntry
+	res   *asyncResult
+	// cached is set when a result for the same stamp was already known.
+	cached segmentData
+	hit    bool
+	// patient is set when the prompt has no value at all for the key and
+	// cannot leave the work to the background.
+	patient bool
+}
+
+// request starts computing the data for key in the background. stamp
+// describes the inputs of compute; it is taken again once compute finishes,
+// so changes compute makes itself, such as git refreshing its index, do not
+// count as new input on the next prompt.
+//
+// A finished result with the same stamp is used as is, and refreshed in the
+// background for the next prompt when refresh is set; leave it unset when
+// the stamp covers every input of compute. Otherwise the prompt waits for the
+// new result until its deadline and then falls back to the last value
+// computed for key.
+func (c *segmentCache) request(key cacheKey, stamp func() string, refresh bool, compute func() segmentData) *pendingSegment {
+	before := stamp()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[key]
+	if e == nil {
+		e = &cacheEntry{}
+		c.entries[key] = e
+	}
+	p := &pendingSegment{c: c, entry: e}
+	if e.done != nil && e.done.stamp == before {
+		p.cached, p.hit = e.done.value, true
+		if !refresh {
+			return p
+		}
+		if e.latest == e.done {
+			c.start(e, before, stamp, compute)
+		}
+		if c.path != "" {
+			// The refresh would die with the process; use it only if it
+			// makes the deadline.
+			p.res = e.latest
+		}
+		return p
+	}
+	p.res = e.latest
+	if p.res == nil || p.res.stamp != before {
+		p.res = c.start(e, before, stamp, compute)
+	}
+	// With an older value to show, a one-shot prompt keeps to its deadline
+	// like any other.
+	p.patient = c.path != "" && e.done == nil
+	return p
+}
+
+// wait returns the data, or ok=false when nothing was computed for the key
+// before deadline.
+func (p *pendingSegment) wait(deadline time.Time) (segmentData, bool) {
+	if p.res == nil {
+		return p.cached, true
+	}
+	if p.patient {
+		deadline = time.Now().Add(firstRunTimeout)
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-p.res.finished:
+		return p.res.value, true
+	case <-timer.C:
+	}
+	if p.hit {
+		return p.cached, true
+	}
+	p.c.mu.Lock()
+	defer p.c.mu.Unlock()
+	p.c.outdated = true
+	if p.entry.done == nil {
+		return segmentData{}, false
+	}
+	return p.entry.done.value, true
+}
+
+// wait blocks until every computation started in c has finished.
+func (c *segmentCache) wait() {
+	c.mu.Lock()
+	var pending []chan struct{}
+	for _, e := range c.entries {
+		if e.latest != nil {
+			pending = append(pending, e.latest.finished)
+		}
+	}
+	c.mu.Unlock()
+	for _, ch := range pending {
+		<-ch
+	}
+}
+
+// start runs compute in a goroutine as the latest result of e, recording
+// the stamp taken after it. c.mu must be held.
+func (c *segmentCache) start(e *cacheEntry, before string, stamp func() string, compute func() segmentData) *asyncResult {
+	res := &asyncResult{stamp: before, finished: make(chan struct{})}
+	e.latest = res
+	go func() {
+		value := compute()
+		after := stamp()
+		c.mu.Lock()
+		res.stamp = after
+		res.value = value
+		res.computed = time.Now()
+		if e.latest == res || e.done == nil {
+			e.done = res
+		}
+		if c.path != "" {
+			c.save()
+		}
+		c.mu.Unlock()
+		close(res.finished)
+	}()
+	return res
+}
+
+// savedSegment is a cache entry as stored on disk.
+type savedSegment struct {
+	Segment  string      `json:"segment"`
+	Dir      string      `json:"dir"`
+	Stamp    string      `json:"stamp"`
+	Computed time.Time   `json:"computed"`
+	Data     segmentData `json:"data"`
+}
+
+// load reads the cache file. A missing or damaged file is an empty cache.
+func (c *segmentCache) load() {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	var saved []savedSegment
+	if json.Unmarshal(data, &saved) != nil {
+		return
+	}
+	for _, s := range saved {
+		res := &asyncResult{stamp: s.Stamp, finished: make(chan struct{}), value: s.Data, computed: s.Computed}
+		close(res.finished)
+		c.entries[cacheKey{s.Segment, s.Dir}] = &cacheEntry{latest: res, done: res}
+	}
+}
+
+// save writes the finished entries to the cache file, newest first,
+// ignoring errors: a lost cache only costs a wait. c.mu must be held.
+func (c *segmentCache) save() {
+	saved := make([]savedSegment, 0, len(c.entries))
+	for key, e := range c.entries {
+		if e.done != nil {
+			saved = append(saved, savedSegment{key.segment, key.dir, e.done.stamp, e.done.computed, e.done.value})
+		}
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Computed.After(saved[j].Computed) })
+	if len(saved) > maxSavedSegments {
+		saved = saved[:maxSavedSegments]
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", c.path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o644); err == nil {
+		os.Rename(tmp, c.path)
+	}
+}
+
+// DefaultCacheFile is where one-shot prompts keep slow segment results.
+func DefaultCacheFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".void", "cache", "segments.json")
+}
+
+// gitStamp describes the state of the repository containing dir by the
+// size and modification time of its index and HEAD, which change on every
+// commit, checkout and stage.
+func gitStamp(dir string) string {
+	gitDir := findGitDir(dir)
+	if gitDir == "" {
+		return "none"
+	}
+	var b strings.Builder
+	b.WriteString(gitDir)
+	for _, name := range []string{"HEAD", "index"} {
+		if fi, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			fmt.Fprintf(&b, "|%s:%d:%d", name, fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	return b.String()
+}
+
+// findGitDir returns the .git directory of the repository containing dir,
+// following the "gitdir:" file used by worktrees and submodules.
+func findGitDir(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ".git")
+		if fi, err := os.Stat(path); err == nil {
+			if fi.IsDir() {
+				return path
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return ""
+			}
+			target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+			if !ok {
+				return ""
+			}
+			target = strings.TrimSpace(target)
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir, target)
+			}
+			return target
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
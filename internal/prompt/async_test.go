@@ -0,0 +1,226 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeGit replaces the git lookup for one test. branch is read on every
+// call, and each call waits for release when it is not nil.
+func fakeGit(t *testing.T, branch *atomic.Value, release chan struct{}) {
+	orig := resolveGitStatusForDir
+	t.Cleanup(func() { resolveGitStatusForDir = orig })
+	resolveGitStatusForDir = func(string) (gitStatus, bool) {
+		if release != nil {
+			<-release
+		}
+		return gitStatus{Head: branch.Load().(string)}, true
+	}
+}
+
+func newRepo(t *testing.T) string {
+	dir := t.TempDir()
+	for _, name := range []string{"HEAD", "index"} {
+		if err := os.MkdirAll(filepath.Join(dir, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".git", name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSlowGitSegmentRendersPlaceholderThenCachedValue(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	var branch atomic.Value
+	branch.Store("main")
+	release := make(chan struct{})
+	fakeGit(t, &branch, release)
+
+	dir := newRepo(t)
+	r := New(Options{Segments: []string{"git"}, Timeout: 20 * time.Millisecond})
+	began := time.Now()
+	out := r.Render(Context{WorkDir: dir})
+	if time.Since(began) > time.Second {
+		t.Fatalf("expected the prompt not to wait for git")
+	}
+	if !strings.Contains(out, gitIcon+" …") || strings.Contains(out, "main") {
+		t.Fatalf("expected a placeholder while git is slow, got %q", out)
+	}
+
+	close(release)
+	r.Wait()
+	if out := r.Render(Context{WorkDir: dir}); !strings.Contains(out, "main") {
+		t.Fatalf("expected the finished git segment, got %q", out)
+	}
+	r.Wait()
+}
+
+func TestGitSegmentCacheFollowsIndexAndHead(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	var branch atomic.Value
+	branch.Store("main")
+	fakeGit(t, &branch, nil)
+
+	dir := newRepo(t)
+	r := New(Options{Segments: []string{"git"}, Timeout: time.Second})
+	if out := r.Render(Context{WorkDir: dir}); !strings.Contains(out, "main") {
+		t.Fatalf("expected main, got %q", out)
+	}
+	r.Wait()
+
+	// Unchanged index and HEAD: the cached value is drawn at once and
+	// refreshed in the background.
+	branch.Store("feature")
+	if out := r.Render(Context{WorkDir: dir}); !strings.Contains(out, "main") {
+		t.Fatalf("expected the cached branch, got %q", out)
+	}
+	r.Wait()
+
+	branch.Store("release")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, ".git", "HEAD"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if out := r.Render(Context{WorkDir: dir}); !strings.Contains(out, "release") {
+		t.Fatalf("expected a HEAD change to invalidate the cache, got %q", out)
+	}
+	r.Wait()
+}
+
+func TestOneShotPromptsShareACacheFile(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	var branch atomic.Value
+	branch.Store("main")
+	orig := resolveGitStatusForDir
+	t.Cleanup(func() { resolveGitStatusForDir = orig })
+	// git takes longer than the prompt timeout, every time.
+	resolveGitStatusForDir = func(string) (gitStatus, bool) {
+		time.Sleep(100 * time.Millisecond)
+		return gitStatus{Head: branch.Load().(string)}, true
+	}
+
+	dir := newRepo(t)
+	file := filepath.Join(t.TempDir(), "segments.json")
+	opts := Options{Segments: []string{"git"}, Timeout: 20 * time.Millisecond, CacheFile: file}
+
+	// Nothing is known yet, so the first prompt waits past its timeout
+	// rather than drawing a placeholder its process will never replace.
+	r := New(opts)
+	if out := r.Render(Context{WorkDir: dir}); !strings.Contains(out, "main") {
+		t.Fatalf("expected the first one-shot prompt to wait for git, got %q", out)
+	}
+	r.Wait()
+
+	// The next process starts from the file and does not wait.
+	branch.Store("feature")
+	r = New(opts)
+	began := time.Now()
+	if out := r.Render(Context{WorkDir: dir}); !strings.Contains(out, "main") {
+		t.Fatalf("expected the saved branch, got %q", out)
+	}
+	if time.Since(began) > 80*time.Millisecond {
+		t.Fatalf("expected a saved value not to wait for git")
+	}
+	r.Wait()
+}
+
+func TestOneShotPromptKeepsDeadlineWhenRepositoryChanges(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	var branch atomic.Value
+	branch.Store("main")
+	dir := newRepo(t)
+	index := filepath.Join(dir, ".git", "index")
+	orig := resolveGitStatusForDir
+	t.Cleanup(func() { resolveGitStatusForDir = orig })
+	// A slow git status that refreshes the index as it goes, as git does.
+	resolveGitStatusForDir = func(string) (gitStatus, bool) {
+		time.Sleep(100 * time.Millisecond)
+		now := time.Now()
+		if err := os.Chtimes(index, now, now); err != nil {
+			t.Error(err)
+		}
+		return gitStatus{Head: branch.Load().(string)}, true
+	}
+	file := filepath.Join(t.TempDir(), "segments.json")
+	opts := Options{Segments: []string{"git"}, Timeout: 20 * time.Millisecond, CacheFile: file}
+	render := func() (*Renderer, string, time.Duration) {
+		r := New(opts)
+		began := time.Now()
+		out := r.Render(Context{WorkDir: dir})
+		return r, out, time.Since(began)
+	}
+
+	r, _, _ := render()
+	r.Wait()
+	// git's own index refresh is not a change.
+	if r, _, took := render(); took > 80*time.Millisecond || r.Outdated() {
+		t.Fatalf("expected the index git rewrote to match the saved value, took %v", took)
+	} else {
+		r.Wait()
+	}
+
+	// A real change draws the old value in time and leaves the update to
+	// a refresh.
+	branch.Store("feature")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, ".git", "HEAD"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	r, out, took := render()
+	if took > 80*time.Millisecond || !strings.Contains(out, "main") || !r.Outdated() {
+		t.Fatalf("expected the saved branch without waiting, got %q after %v", out, took)
+	}
+	r.Wait()
+	r, out, _ = render()
+	r.Wait()
+	if !strings.Contains(out, "feature") {
+		t.Fatalf("expected the refreshed branch, got %q", out)
+	}
+}
+
+func TestWithKeepsSegmentCache(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	var branch atomic.Value
+	branch.Store("main")
+	fakeGit(t, &branch, nil)
+
+	dir := newRepo(t)
+	r := New(Options{Segments: []string{"git"}, Timeout: time.Second})
+	r.Render(Context{WorkDir: dir})
+	r.Wait()
+
+	// The new renderer draws the cached result and refreshes it behind the
+	// prompt, as r would have.
+	branch.Store("dev")
+	r = r.With(Options{Segments: []string{"path", "git"}, Symbol: "$", Timeout: time.Second})
+	if out := r.Render(Context{WorkDir: dir}); !strings.Contains(out, "main") || !strings.Contains(out, "$") {
+		t.Fatalf("expected the cached git segment with the new options, got %q", out)
+	}
+	r.Wait()
+}
+
+func TestFindGitDirFollowsGitFile(t *testing.T) {
+	repo := newRepo(t)
+	worktree := t.TempDir()
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+filepath.Join(repo, ".git")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(worktree, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := findGitDir(sub); got != filepath.Join(repo, ".git") {
+		t.Fatalf("expected %s, got %q", filepath.Join(repo, ".git"), got)
+	}
+}
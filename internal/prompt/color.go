@@ -0,0 +1,206 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// colorMode is how many colours the terminal can show.
+type colorMode int
+
+const (
+	colorTrue colorMode = iota
+	color256
+	color16
+)
+
+// maxPaletteDepth bounds palette references so a cycle cannot loop forever.
+const maxPaletteDepth = 8
+
+// parseColorMode maps the prompt.color_mode setting to a mode, detecting it
+// from the environment for "auto" or an empty value.
+func parseColorMode(setting string) colorMode {
+	switch strings.ToLower(strings.TrimSpace(setting)) {
+	case "truecolor", "24bit":
+		return colorTrue
+	case "256":
+		return color256
+	case "16":
+		return color16
+	}
+	return detectColorMode()
+}
+
+// detectColorMode follows the COLORTERM and TERM conventions. Without TERM,
+// as in Windows consoles, 24-bit colour is assumed.
+func detectColorMode() colorMode {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return colorTrue
+	}
+	if os.Getenv("WT_SESSION") != "" {
+		return colorTrue
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode":
+		return colorTrue
+	}
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case term == "":
+		return colorTrue
+	case strings.Contains(term, "direct") || strings.Contains(term, "truecolor"):
+		return colorTrue
+	case strings.Contains(term, "256"):
+		return color256
+	}
+	return color16
+}
+
+// resolvePalette replaces palette references and colour names with
+// "#rrggbb" values. Values that cannot be resolved are kept as written.
+func resolvePalette(palette map[string]string) map[string]string {
+	out := make(map[string]string, len(palette))
+	for k, v := range palette {
+		if hex := resolveColor(palette, v, 0); hex != "" {
+			out[k] = hex
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// resolveColor turns a palette value into "#rrggbb": a hex colour, the name
+// of another palette entry, or a CSS colour name.
+func resolveColor(palette map[string]string, value string, depth int) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	if strings.HasPrefix(value, "#") {
+		if c, ok := parseHex(value); ok {
+			return c.hex()
+		}
+		return ""
+	}
+	if ref, ok := palette[value]; ok && depth < maxPaletteDepth {
+		return resolveColor(palette, ref, depth+1)
+	}
+	return namedColors[strings.ToLower(value)]
+}
+
+type rgb struct {
+	r, g, b uint8
+}
+
+func (c rgb) hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.r, c.g, c.b)
+}
+
+// parseHex accepts "#rrggbb" and the short "#rgb" form.
+func parseHex(s string) (rgb, bool) {
+	if !strings.HasPrefix(s, "#") {
+		return rgb{}, false
+	}
+	s = s[1:]
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return rgb{}, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return rgb{}, false
+	}
+	return rgb{uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+}
+
+// sgr returns the escape sequence that sets color as the foreground
+// (prefix "38") or background ("48") in mode m.
+func (m colorMode) sgr(prefix, color string) string {
+	if m == colorTrue {
+		return ansiRGB(prefix, color)
+	}
+	c, ok := parseHex(color)
+	if !ok || len(color) != 7 {
+		return ""
+	}
+	if m == color256 {
+		return fmt.Sprintf("\x1b[%s;5;%dm", prefix, to256(c))
+	}
+	i := to16(c)
+	base := 30
+	if prefix == "48" {
+		base = 40
+	}
+	if i >= 8 {
+		base += 60
+		i -= 8
+	}
+	return fmt.Sprintf("\x1b[%dm", base+i)
+}
+
+func (m colorMode) seq(fg, bg string) string {
+	return m.sgr("38", fg) + m.sgr("48", bg)
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// to256 picks the closest entry of the xterm 6x6x6 colour cube or grey ramp.
+func to256(c rgb) int {
+	cubeIndex := func(v uint8) int {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+	ri, gi, bi := cubeIndex(c.r), cubeIndex(c.g), cubeIndex(c.b)
+	cube := rgb{uint8(cubeLevels[ri]), uint8(cubeLevels[gi]), uint8(cubeLevels[bi])}
+
+	avg := (int(c.r) + int(c.g) + int(c.b)) / 3
+	grayIndex := 23
+	if avg < 238 {
+		grayIndex = max(0, (avg-3)/10)
+	}
+	gv := uint8(8 + 10*grayIndex)
+	gray := rgb{gv, gv, gv}
+
+	if distance(c, gray) < distance(c, cube) {
+		return 232 + grayIndex
+	}
+	return 16 + 36*ri + 6*gi + bi
+}
+
+// ansi16 are the xterm defaults for the 16 basic colours.
+var ansi16 = [16]rgb{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+func to16(c rgb) int {
+	best, bestDist := 0, -1
+	for i, candidate := range ansi16 {
+		if d := distance(c, candidate); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// distance is a perceptually weighted squared distance ("redmean").
+func distance(a, b rgb) int {
+	rmean := (int(a.r) + int(b.r)) / 2
+	dr := int(a.r) - int(b.r)
+	dg := int(a.g) - int(b.g)
+	db := int(a.b) - int(b.b)
+	return ((512+rmean)*dr*dr)>>8 + 4*dg*dg + ((767-rmean)*db*db)>>8
+}
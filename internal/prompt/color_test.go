@@ -0,0 +1,103 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolvePaletteFollowsReferencesAndNames(t *testing.T) {
+	got := resolvePalette(map[string]string{
+		"accent":    "#0AF",
+		"path_bg":   "accent",
+		"path_fg":   "White",
+		"user_bg":   "path_bg",
+		"loop_a":    "loop_b",
+		"loop_b":    "loop_a",
+		"git_bg":    "not-a-colour",
+		"time_bg":   "#12345",
+		"symbol_fg": "rebeccapurple",
+	})
+	want := map[string]string{
+		"accent":    "#00aaff",
+		"path_bg":   "#00aaff",
+		"path_fg":   "#ffffff",
+		"user_bg":   "#00aaff",
+		"loop_a":    "loop_b",
+		"loop_b":    "loop_a",
+		"git_bg":    "not-a-colour",
+		"time_bg":   "#12345",
+		"symbol_fg": "#663399",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s: got %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestColorModeDownsamples(t *testing.T) {
+	tests := []struct {
+		mode   colorMode
+		prefix string
+		color  string
+		want   string
+	}{
+		{colorTrue, "38", "#ff8000", "\x1b[38;2;255;128;0m"},
+		{color256, "38", "#ff0000", "\x1b[38;5;196m"},
+		{color256, "48", "#000000", "\x1b[48;5;16m"},
+		{color256, "48", "#808080", "\x1b[48;5;244m"},
+		{color16, "38", "#ff0000", "\x1b[91m"},
+		{color16, "48", "#000000", "\x1b[40m"},
+		{color16, "48", "#cd00cd", "\x1b[45m"},
+		{color16, "38", "#fafafa", "\x1b[97m"},
+		{color256, "38", "blue", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.sgr(tt.prefix, tt.color); got != tt.want {
+			t.Fatalf("mode %d %s %s: got %q, want %q", tt.mode, tt.prefix, tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestDetectColorMode(t *testing.T) {
+	tests := []struct {
+		colorterm, term string
+		want            colorMode
+	}{
+		{"truecolor", "xterm", colorTrue},
+		{"24bit", "screen", colorTrue},
+		{"", "xterm-direct", colorTrue},
+		{"", "tmux-256color", color256},
+		{"", "xterm", color16},
+		{"", "", colorTrue},
+	}
+	t.Setenv("WT_SESSION", "")
+	t.Setenv("TERM_PROGRAM", "")
+	for _, tt := range tests {
+		t.Setenv("COLORTERM", tt.colorterm)
+		t.Setenv("TERM", tt.term)
+		if got := detectColorMode(); got != tt.want {
+			t.Fatalf("COLORTERM=%q TERM=%q: got %d, want %d", tt.colorterm, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestRendererUsesConfiguredColorMode(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	t.Setenv("COLORTERM", "truecolor")
+
+	r := New(Options{
+		Segments:  []string{"path"},
+		Symbol:    ">",
+		Palette:   map[string]string{"accent": "#ff0000", "path_bg_1": "accent", "symbol_fg": "white"},
+		ColorMode: "256",
+	})
+	out := r.Render(Context{WorkDir: "/tmp"})
+	if strings.Contains(out, ";2;") {
+		t.Fatalf("expected no 24-bit colours in 256-colour mode, got %q", out)
+	}
+	if !strings.Contains(out, "\x1b[48;5;196m") || !strings.Contains(out, "\x1b[38;5;231m>") {
+		t.Fatalf("expected resolved palette colours as 256-colour codes, got %q", out)
+	}
+}
@@ -0,0 +1,50 @@
+package prompt
+
+import (
+	"fmt"
+	"time"
+)
+
+// formatDuration renders d for the duration segment: "850ms", "4.2s",
+// "1m05s" or "2h03m".
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	if tenths := d.Round(100 * time.Millisecond); tenths < 10*time.Second {
+		return fmt.Sprintf("%.1fs", tenths.Seconds())
+	}
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// Notification returns the escape sequence that signals the end of a long
+// command, or "" when notifications are off or the command was quick. It is
+// written to the terminal apart from the prompt so it never takes up room
+// on the prompt line.
+func (r *Renderer) Notification(ctx Context) string {
+	if r.opts.NotifyAfter <= 0 || ctx.Duration < r.opts.NotifyAfter {
+		return ""
+	}
+	switch r.opts.Notify {
+	case "bell":
+		return "\a"
+	case "desktop":
+		status := "finished"
+		if ctx.LastExitCode != 0 {
+			status = fmt.Sprintf("failed with code %d", ctx.LastExitCode)
+		}
+		msg := fmt.Sprintf("Command %s after %s", status, formatDuration(ctx.Duration))
+		// OSC 9 is shown as a desktop notification by iTerm2, WezTerm,
+		// kitty, ConEmu and others; the rest ignore it.
+		return "\x1b]9;" + msg + "\a"
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/void-shell/void/internal/config"
+)
+
+func TestFormatDuration(t *testing.T) {
+	cases := map[time.Duration]string{
+		850 * time.Millisecond:                      "850ms",
+		4200 * time.Millisecond:                     "4.2s",
+		9960 * time.Millisecond:                     "10s",
+		42 * time.Second:                            "42s",
+		65 * time.Second:                            "1m05s",
+		2*time.Hour + 3*time.Minute + 9*time.Second: "2h03m",
+	}
+	for d, want := range cases {
+		if got := formatDuration(d); got != want {
+			t.Fatalf("formatDuration(%v): expected %q, got %q", d, want, got)
+		}
+	}
+}
+
+func TestDurationSegmentThreshold(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	r := New(Options{Segments: []string{"duration"}})
+	if out := r.Render(Context{Duration: 1500 * time.Millisecond}); strings.Contains(out, "1.5s") {
+		t.Fatalf("expected no duration below the default threshold, got %q", out)
+	}
+	if out := r.Render(Context{Duration: 3 * time.Second}); !strings.Contains(out, clockIcon+" 3.0s") {
+		t.Fatalf("expected the duration segment, got %q", out)
+	}
+
+	r = New(Options{Segments: []string{"duration"}, Segment: map[string]config.SegmentConfig{"duration": {Threshold: 100}}})
+	if out := r.Render(Context{Duration: 150 * time.Millisecond}); !strings.Contains(out, "150ms") {
+		t.Fatalf("expected a configured threshold to apply, got %q", out)
+	}
+}
+
+func TestNotification(t *testing.T) {
+	ctx := Context{LastExitCode: 2, Duration: 12 * time.Second}
+	if got := New(Options{Notify: "bell", NotifyAfter: 10 * time.Second}).Notification(ctx); got != "\a" {
+		t.Fatalf("expected a bell, got %q", got)
+	}
+	got := New(Options{Notify: "desktop", NotifyAfter: 10 * time.Second}).Notification(ctx)
+	if got != "\x1b]9;Command failed with code 2 after 12s\a" {
+		t.Fatalf("unexpected desktop notification %q", got)
+	}
+	if got := New(Options{Notify: "bell", NotifyAfter: 20 * time.Second}).Notification(ctx); got != "" {
+		t.Fatalf("expected no notification for a quick command, got %q", got)
+	}
+	if got := New(Options{Notify: "off", NotifyAfter: 10 * time.Second}).Notification(ctx); got != "" {
+		t.Fatalf("expected notifications to be off, got %q", got)
+	}
+}
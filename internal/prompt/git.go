@@ -0,0 +1,211 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Git status indicators, as listed in prompt.git_indicators.
+const (
+	gitAhead      = "ahead"
+	gitBehind     = "behind"
+	gitStaged     = "staged"
+	gitModified   = "modified"
+	gitUntracked  = "untracked"
+	gitConflicted = "conflicted"
+	gitStash      = "stash"
+	gitOperation  = "operation"
+)
+
+// gitStatus is what the git segment shows about a repository.
+type gitStatus struct {
+	// Head is the branch name, or the tag or short commit of a detached HEAD.
+	Head     string
+	Detached bool
+
+	Ahead, Behind int
+	Staged        int
+	Modified      int
+	Untracked     int
+	Conflicted    int
+	Stash         int
+	// Operation is "rebase", "merge", "cherry-pick", "revert" or "bisect"
+	// while one is in progress.
+	Operation string
+}
+
+func (s gitStatus) dirty() bool {
+	return s.Staged+s.Modified+s.Untracked+s.Conflicted > 0
+}
+
+// detectGitStatus reads the state of the repository containing dir from
+// `git status --porcelain=v2`. ok is false outside a repository.
+func detectGitStatus(dir string) (gitStatus, bool) {
+	gitDir := findGitDir(dir)
+	if gitDir == "" {
+		return gitStatus{}, false
+	}
+	out, err := exec.Command("git", "-C", dir, "status", "--porcelain=v2", "--branch", "--show-stash").Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && bytes.Contains(exitErr.Stderr, []byte("show-stash")) {
+		// --show-stash needs git 2.35.
+		out, err = exec.Command("git", "-C", dir, "status", "--porcelain=v2", "--branch").Output()
+	}
+	if err != nil {
+		return gitStatus{}, false
+	}
+	s, oid := parseGitStatus(out)
+	if s.Detached {
+		s.Head = shortCommit(oid)
+		tag, err := exec.Command("git", "-C", dir, "describe", "--tags", "--exact-match", "HEAD").Output()
+		if err == nil && len(bytes.TrimSpace(tag)) > 0 {
+			s.Head = string(bytes.TrimSpace(tag))
+		}
+	}
+	s.Operation = gitOperationIn(gitDir)
+	return s, true
+}
+
+// parseGitStatus parses `git status --porcelain=v2 --branch --show-stash`
+// output and returns the status with the commit HEAD points to.
+func parseGitStatus(out []byte) (gitStatus, string) {
+	var s gitStatus
+	var oid string
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			key, value, _ := strings.Cut(header, " ")
+			switch key {
+			case "branch.oid":
+				oid = value
+			case "branch.head":
+				if value == "(detached)" {
+					s.Detached = true
+				} else {
+					s.Head = value
+				}
+			case "branch.ab":
+				for _, f := range strings.Fields(value) {
+					n, _ := strconv.Atoi(f[1:])
+					if f[0] == '+' {
+						s.Ahead = n
+					} else {
+						s.Behind = n
+					}
+				}
+			case "stash":
+				s.Stash, _ = strconv.Atoi(value)
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case '1', '2':
+			if len(line) < 4 {
+				continue
+			}
+			if line[2] != '.' {
+				s.Staged++
+			}
+			if line[3] != '.' {
+				s.Modified++
+			}
+		case 'u':
+			s.Conflicted++
+		case '?':
+			s.Untracked++
+		}
+	}
+	return s, oid
+}
+
+func shortCommit(oid string) string {
+	if len(oid) > 7 {
+		return oid[:7]
+	}
+	return oid
+}
+
+// gitOperationIn returns the operation in progress in gitDir, if any.
+func gitOperationIn(gitDir string) string {
+	if gitDir == "" {
+		return ""
+	}
+	for _, op := range []struct{ file, name string }{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+		{"BISECT_LOG", "bisect"},
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, op.file)); err == nil {
+			return op.name
+		}
+	}
+	return ""
+}
+
+// gitIndicators renders the enabled indicators of s, each coloured with its
+// git_<indicator>_fg palette entry. fg is the segment's own colour, restored
+// after each indicator.
+func (r *Renderer) gitIndicators(s gitStatus, fg string) string {
+	unicodeOK := supportsUnicodePrompt()
+	symbol := func(unicode, ascii string) string {
+		if unicodeOK {
+			return unicode
+		}
+		return ascii
+	}
+	restore := r.mode.sgr("38", fg)
+	if restore == "" {
+		restore = "\x1b[39m"
+	}
+
+	var parts []string
+	for _, name := range r.opts.GitIndicators {
+		var text string
+		switch name {
+		case gitAhead:
+			text = countIndicator(symbol("↑", "^"), s.Ahead)
+		case gitBehind:
+			text = countIndicator(symbol("↓", "v"), s.Behind)
+		case gitStaged:
+			text = countIndicator("+", s.Staged)
+		case gitModified:
+			text = countIndicator("!", s.Modified)
+		case gitUntracked:
+			text = countIndicator("?", s.Untracked)
+		case gitConflicted:
+			text = countIndicator("=", s.Conflicted)
+		case gitStash:
+			text = countIndicator(symbol("≡", "$"), s.Stash)
+		case gitOperation:
+			text = strings.ToUpper(s.Operation)
+		}
+		if text == "" {
+			continue
+		}
+		if color := r.mode.sgr("38", r.palette["git_"+name+"_fg"]); color != "" {
+			text = color + text + restore
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func countIndicator(symbol string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return symbol + strconv.Itoa(n)
+}
@@ -0,0 +1,160 @@
+package prompt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const porcelainV2 = `# branch.oid 4b825dc642cb6eb9a060e54bf8d69288fbee4904
+# branch.head main
+# branch.upstream origin/main
+# branch.ab +2 -1
+# stash 3
+1 M. N... 100644 100644 100644 aaaaaaa bbbbbbb staged.go
+1 .M N... 100644 100644 100644 aaaaaaa bbbbbbb edited.go
+1 MM N... 100644 100644 100644 aaaaaaa bbbbbbb both.go
+2 R. N... 100644 100644 100644 aaaaaaa bbbbbbb R100 new.go	old.go
+u UU N... 100644 100644 100644 100644 aaaaaaa bbbbbbb ccccccc conflict.go
+? notes.txt
+? tmp/
+`
+
+func TestParseGitStatus(t *testing.T) {
+	s, oid := parseGitStatus([]byte(porcelainV2))
+	want := gitStatus{Head: "main", Ahead: 2, Behind: 1, Staged: 3, Modified: 2, Untracked: 2, Conflicted: 1, Stash: 3}
+	if s != want {
+		t.Fatalf("got %+v, want %+v", s, want)
+	}
+	if oid != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" {
+		t.Fatalf("unexpected oid %q", oid)
+	}
+
+	s, oid = parseGitStatus([]byte("# branch.oid 0123456789abcdef\n# branch.head (detached)\n"))
+	if !s.Detached || s.Head != "" || shortCommit(oid) != "0123456" {
+		t.Fatalf("expected a detached HEAD, got %+v %q", s, oid)
+	}
+}
+
+func TestGitOperationIn(t *testing.T) {
+	dir := t.TempDir()
+	if got := gitOperationIn(dir); got != "" {
+		t.Fatalf("expected no operation, got %q", got)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "rebase-merge"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitOperationIn(dir); got != "rebase" {
+		t.Fatalf("expected rebase, got %q", got)
+	}
+}
+
+func TestGitIndicatorsFollowConfigAndPalette(t *testing.T) {
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	t.Setenv("TERM_PROGRAM", "")
+	s := gitStatus{Head: "main", Ahead: 1, Staged: 2, Untracked: 4, Operation: "merge"}
+
+	r := New(Options{ColorMode: "truecolor"})
+	if got := r.gitIndicators(s, ""); got != "↑1 +2 ?4 MERGE" {
+		t.Fatalf("unexpected indicators %q", got)
+	}
+
+	r = New(Options{
+		ColorMode:     "truecolor",
+		GitIndicators: []string{"staged", "operation"},
+		Palette:       map[string]string{"git_staged_fg": "#00ff00"},
+	})
+	got := r.gitIndicators(s, "#ffffff")
+	if got != "\x1b[38;2;0;255;0m+2\x1b[38;2;255;255;255m MERGE" {
+		t.Fatalf("unexpected coloured indicators %q", got)
+	}
+}
+
+func TestDetectGitStatusInRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "first")
+	git("tag", "v1.0.0")
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := detectGitStatus(dir)
+	if !ok || s.Head != "main" || s.Untracked != 1 || s.Detached {
+		t.Fatalf("unexpected status %+v", s)
+	}
+
+	git("checkout", "-q", "--detach", "HEAD")
+	s, ok = detectGitStatus(dir)
+	if !ok || !s.Detached || s.Head != "v1.0.0" {
+		t.Fatalf("expected detached HEAD at the tag, got %+v", s)
+	}
+
+	if _, ok := detectGitStatus(t.TempDir()); ok {
+		t.Fatal("expected no status outside a repository")
+	}
+}
+
+func TestDetectGitStatusRunsGitOncePerAttempt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake git is a shell script")
+	}
+	// A git older than 2.35, which rejects --show-stash.
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	writeFile(t, filepath.Join(bin, "git"), `#!/bin/sh
+echo "$*" >> "`+calls+`"
+case "$*" in
+*--show-stash*) echo "error: unknown option \`+"`show-stash'"+`" >&2; exit 129 ;;
+esac
+printf '# branch.oid 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n# branch.head main\n'
+`)
+	if err := os.Chmod(filepath.Join(bin, "git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	countCalls := func() int {
+		data, _ := os.ReadFile(calls)
+		return strings.Count(string(data), "\n")
+	}
+
+	if _, ok := detectGitStatus(t.TempDir()); ok || countCalls() != 0 {
+		t.Fatalf("expected git not to run outside a repository, ran %d times", countCalls())
+	}
+	if s, ok := detectGitStatus(newRepo(t)); !ok || s.Head != "main" || countCalls() != 2 {
+		t.Fatalf("expected a retry without --show-stash, got %+v after %d runs", s, countCalls())
+	}
+}
+
+func TestGitSegmentShowsStatus(t *testing.T) {
+	t.Setenv("VOID_PROMPT_UNICODE", "0")
+	t.Setenv("TERM_PROGRAM", "")
+	orig := resolveGitStatusForDir
+	t.Cleanup(func() { resolveGitStatusForDir = orig })
+	resolveGitStatusForDir = func(string) (gitStatus, bool) {
+		return gitStatus{Head: "abc1234", Detached: true, Behind: 2, Modified: 1}, true
+	}
+
+	r := New(Options{Segments: []string{"git"}})
+	out := r.Render(Context{WorkDir: t.TempDir()})
+	r.Wait()
+	if !strings.Contains(out, "abc1234 v2 !1") {
+		t.Fatalf("expected the short commit and indicators, got %q", out)
+	}
+}
@@ -0,0 +1,73 @@
+package prompt
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// Path colour strategies for prompt.path_colors.
+const (
+	pathColorsGradient    = "gradient"
+	pathColorsInterpolate = "interpolate"
+	pathColorsHash        = "hash"
+)
+
+// pathColors chooses breadcrumb backgrounds. The colours are worked out once
+// per Renderer, so the same directory always looks the same.
+type pathColors struct {
+	strategy string
+	colors   []string
+	// from and to are the ends of an interpolated path.
+	from, to rgb
+}
+
+// newPathColors builds the colours for strategy from palette.
+//
+//   - gradient: path_bg_1..path_bg_20 in order, or path_bg followed by the
+//     built-in colours, repeating for deep paths.
+//   - interpolate: an even blend from path_bg_start (or path_bg) to
+//     path_bg_end, spread over however many breadcrumbs there are.
+//   - hash: a colour from the gradient picked by the breadcrumb's name.
+//
+// interpolate without two valid end colours falls back to gradient.
+func newPathColors(strategy string, palette map[string]string) pathColors {
+	switch strategy {
+	case pathColorsInterpolate:
+		start := palette["path_bg_start"]
+		if start == "" {
+			start = palette["path_bg"]
+		}
+		from, okFrom := parseHex(strings.TrimSpace(start))
+		to, okTo := parseHex(strings.TrimSpace(palette["path_bg_end"]))
+		if okFrom && okTo {
+			return pathColors{strategy: strategy, from: from, to: to}
+		}
+	case pathColorsHash:
+		return pathColors{strategy: strategy, colors: pathGradient(palette)}
+	}
+	return pathColors{strategy: pathColorsGradient, colors: pathGradient(palette)}
+}
+
+// pick returns the background of breadcrumb i of n, called name.
+func (p pathColors) pick(i, n int, name string) string {
+	switch p.strategy {
+	case pathColorsInterpolate:
+		if n <= 1 {
+			return p.from.hex()
+		}
+		return blend(p.from, p.to, float64(i)/float64(n-1)).hex()
+	case pathColorsHash:
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		return p.colors[h.Sum32()%uint32(len(p.colors))]
+	}
+	return p.colors[i%len(p.colors)]
+}
+
+// blend mixes a and b, going from a at t=0 to b at t=1.
+func blend(a, b rgb, t float64) rgb {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+	return rgb{mix(a.r, b.r), mix(a.g, b.g), mix(a.b, b.b)}
+}
@@ -0,0 +1,52 @@
+package prompt
+
+import "testing"
+
+func TestGradientPathColorsAreStable(t *testing.T) {
+	palette := map[string]string{"path_bg": "#ff00aa"}
+	first := renderPathSegments(renderPathParts("/srv/app/src"), palette, newPathColors("gradient", palette))
+	second := renderPathSegments(renderPathParts("/srv/app/src"), palette, newPathColors("gradient", palette))
+	for i := range first {
+		if first[i].bg != second[i].bg {
+			t.Fatalf("breadcrumb %d changed colour: %q then %q", i, first[i].bg, second[i].bg)
+		}
+	}
+	if first[0].bg != "#ff00aa" {
+		t.Fatalf("expected path_bg first, got %q", first[0].bg)
+	}
+}
+
+func TestInterpolatePathColorsSpanBothEnds(t *testing.T) {
+	palette := map[string]string{"path_bg_start": "#000000", "path_bg_end": "#ffffff"}
+	colors := newPathColors("interpolate", palette)
+	for _, n := range []int{2, 3, 7} {
+		if got := colors.pick(0, n, ""); got != "#000000" {
+			t.Fatalf("n=%d: expected start colour first, got %q", n, got)
+		}
+		if got := colors.pick(n-1, n, ""); got != "#ffffff" {
+			t.Fatalf("n=%d: expected end colour last, got %q", n, got)
+		}
+	}
+	if got := colors.pick(1, 3, ""); got != "#808080" {
+		t.Fatalf("expected midpoint #808080, got %q", got)
+	}
+
+	fallback := newPathColors("interpolate", map[string]string{"path_bg_1": "#111111"})
+	if fallback.strategy != pathColorsGradient || fallback.pick(0, 3, "") != "#111111" {
+		t.Fatalf("expected gradient fallback without an end colour, got %#v", fallback)
+	}
+}
+
+func TestHashPathColorsFollowTheName(t *testing.T) {
+	colors := newPathColors("hash", map[string]string{})
+	if colors.pick(0, 5, "src") != colors.pick(3, 9, "src") {
+		t.Fatal("expected the same name to get the same colour at any depth")
+	}
+	seen := map[string]bool{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		seen[colors.pick(0, 1, name)] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected different names to spread over the colours, got %v", seen)
+	}
+}
@@ -2,7 +2,6 @@ package prompt
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"os/exec"
 	"os/user"
@@ -11,6 +10,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/void-shell/void/internal/config"
 )
 
 const (
@@ -22,6 +24,7 @@ const (
 	folderIcon = "■"
 	timeIcon   = "◷"
 	errorIcon  = "✕"
+	clockIcon  = "⧗"
 
 	segmentSeparatorASCII = ">"
 	promptLinePrefix      = "│ "
@@ -38,6 +41,11 @@ const (
 type Context struct {
 	LastExitCode int
 	WorkDir      string
+	// Duration is how long the last command ran; zero when unknown.
+	Duration time.Duration
+	// Columns is the terminal width used to align right segments. Zero
+	// means detect it.
+	Columns int
 }
 
 type renderSegment struct {
@@ -49,68 +57,255 @@ type renderSegment struct {
 var (
 	resolveGitBranchForDir = detectGitBranchForDir
 	resolveGitDirtyForDir  = detectGitDirtyForDir
+	resolveGitStatusForDir = detectGitStatus
 	resolveCurrentUser     = user.Current
 	resolveHostname        = os.Hostname
 )
 
+// Options configures a Renderer.
+type Options struct {
+	Segments []string
+	// RightSegments are aligned to the right edge of the badge line.
+	RightSegments []string
+	// Transient turns on the one-line prompt returned by Transient.
+	Transient bool
+	Symbol    string
+	Palette   map[string]string
+	// ColorMode is "truecolor", "256", "16" or "auto" to detect it from
+	// COLORTERM and TERM.
+	ColorMode string
+	// PathColors is how breadcrumbs are coloured: "gradient",
+	// "interpolate" or "hash".
+	PathColors string
+	// GitIndicators are the git status indicators to show, in order. Nil
+	// means all of them.
+	GitIndicators []string
+	// Timeout is how long slow segments such as git may take before the
+	// prompt is drawn with their last known value. Zero means 200ms.
+	Timeout time.Duration
+	// Notify is "bell" or "desktop" to signal that a command running at
+	// least NotifyAfter has finished; anything else turns it off.
+	Notify      string
+	NotifyAfter time.Duration
+	// CacheFile, when set, keeps slow segment results between processes.
+	// One-shot callers such as `void prompt` set it; see segmentCache.
+	CacheFile string
+	// Segment styles segments by name. Segments missing from it use the
+	// defaults from config.Default.
+	Segment map[string]config.SegmentConfig
+}
+
+// OptionsFromConfig returns the prompt options set in cfg.
+func OptionsFromConfig(cfg config.Config) Options {
+	return Options{
+		Segments:      cfg.Prompt.Segments,
+		RightSegments: cfg.Prompt.RightSegments,
+		Transient:     cfg.Prompt.Transient,
+		Symbol:        cfg.Prompt.Symbol,
+		Palette:       cfg.Palette,
+		ColorMode:     cfg.Prompt.ColorMode,
+		PathColors:    cfg.Prompt.PathColors,
+		GitIndicators: cfg.Prompt.GitIndicators,
+		Timeout:       time.Duration(cfg.Prompt.Timeout) * time.Millisecond,
+		Notify:        cfg.Prompt.Notify,
+		NotifyAfter:   time.Duration(cfg.Prompt.NotifyAfter) * time.Second,
+		Segment:       cfg.Segment,
+	}
+}
+
+// Renderer draws the prompt for one set of options. Palette references and
+// the colour mode are resolved once, when the renderer is built.
+type Renderer struct {
+	opts    Options
+	palette map[string]string
+	mode    colorMode
+	path    pathColors
+	styles  map[string]config.SegmentConfig
+	cache   *segmentCache
+}
+
+func New(opts Options) *Renderer {
+	return newRenderer(opts, newSegmentCache(opts.CacheFile))
+}
+
+// With returns a renderer for opts that shares r's segment cache, so git
+// and runtime results carry over when the options change. opts.CacheFile
+// is ignored.
+func (r *Renderer) With(opts Options) *Renderer {
+	opts.CacheFile = r.opts.CacheFile
+	return newRenderer(opts, r.cache)
+}
+
+func newRenderer(opts Options, cache *segmentCache) *Renderer {
+	defaults := config.Default()
+	if opts.GitIndicators == nil {
+		opts.GitIndicators = defaults.Prompt.GitIndicators
+	}
+	palette := resolvePalette(opts.Palette)
+	styles := map[string]config.SegmentConfig{}
+	for name, style := range defaults.Segment {
+		styles[name] = style
+	}
+	for name, style := range opts.Segment {
+		styles[name] = style
+	}
+	for name, style := range styles {
+		if style.FG != "" {
+			style.FG = resolveColor(palette, style.FG, 0)
+		}
+		if style.BG != "" {
+			style.BG = resolveColor(palette, style.BG, 0)
+		}
+		styles[name] = style
+	}
+	return &Renderer{
+		opts:    opts,
+		palette: palette,
+		mode:    parseColorMode(opts.ColorMode),
+		path:    newPathColors(opts.PathColors, palette),
+		styles:  styles,
+		cache:   cache,
+	}
+}
+
 func Render(segments []string, symbol string, palette map[string]string, ctx Context) string {
+	return New(Options{Segments: segments, Symbol: symbol, Palette: palette}).Render(ctx)
+}
+
+func (r *Renderer) Render(ctx Context) string {
+	unicodeOK := supportsUnicodePrompt()
+	wd := ctx.WorkDir
+	if wd == "" {
+		wd, _ = os.Getwd()
+	}
+
+	// Slow segments start first and run while the rest of the prompt is
+	// built.
+	start := time.Now()
+	names := append(append([]string{}, r.opts.Segments...), r.opts.RightSegments...)
+	pending := make([]*pendingSegment, len(names))
+	for i, segment := range names {
+		if !visible(r.styles[segment].When, ctx) {
+			continue
+		}
+		if segment == "git" {
+			pending[i] = r.cache.request(cacheKey{segment, wd}, func() string { return gitStamp(wd) }, true, func() segmentData {
+				return gitData(wd)
+			})
+		}
+		if spec, ok := runtimes[segment]; ok {
+			if stamp, ok := runtimeStamp(segment, spec, wd); ok {
+				pending[i] = r.cache.request(cacheKey{segment, wd}, func() string { return stamp }, false, func() segmentData {
+					return segmentData{Version: runtimeVersion(spec, wd)}
+				})
+			}
+		}
+	}
+
+	split := len(r.opts.Segments)
+	rendered := r.renderSegments(names[:split], pending[:split], ctx, wd, start)
+	right := r.renderSegments(names[split:], pending[split:], ctx, wd, start)
+	symbolSegment := r.symbolSegment(unicodeOK)
+
+	if len(rendered) == 0 && len(right) == 0 {
+		return renderWithArrows([]renderSegment{symbolSegment}, unicodeOK, r.mode)
+	}
+
+	badges := strings.TrimRight(renderWithArrows(rendered, unicodeOK, r.mode), " ")
+	if len(right) > 0 {
+		columns := ctx.Columns
+		if columns <= 0 {
+			columns = terminalColumns()
+		}
+		badges = alignRight(badges, renderRightSegments(right, unicodeOK, r.mode), columns)
+	}
+	promptSymbol := strings.TrimLeft(renderWithArrows([]renderSegment{symbolSegment}, unicodeOK, r.mode), " ")
+
+	return badges + "\n" + promptLinePrefix + promptSymbol
+}
+
+// Outdated reports whether a prompt drawn by r showed an old value, or a
+// placeholder, because a slow segment had not finished in time. One-shot
+// callers finish that work with Wait in a process of their own.
+func (r *Renderer) Outdated() bool {
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+	return r.cache.outdated
+}
+
+// Wait blocks until the slow segments started by r have finished, and with
+// Options.CacheFile set, have been saved.
+func (r *Renderer) Wait() {
+	r.cache.wait()
+}
+
+// Transient returns the one-line prompt that replaces a submitted prompt
+// when prompt.transient is on, or "" when it is off.
+func (r *Renderer) Transient() string {
+	if !r.opts.Transient {
+		return ""
+	}
 	unicodeOK := supportsUnicodePrompt()
-	userPromptIcon := promptIcon(userIcon)
-	gitPromptIcon := promptIcon(gitIcon)
-	timePromptIcon := promptIcon(timeIcon)
-	errorPromptIcon := promptIcon(errorIcon)
+	return strings.TrimLeft(renderWithArrows([]renderSegment{r.symbolSegment(unicodeOK)}, unicodeOK, r.mode), " ")
+}
 
-	rendered := make([]renderSegment, 0, len(segments))
-	for _, segment := range segments {
+// renderSegments renders the visible segments among names. pending holds
+// the slow segments started for them, by position.
+func (r *Renderer) renderSegments(names []string, pending []*pendingSegment, ctx Context, wd string, start time.Time) []renderSegment {
+	rendered := make([]renderSegment, 0, len(names))
+	for i, segment := range names {
+		if !visible(r.styles[segment].When, ctx) {
+			continue
+		}
+		if pending[i] != nil {
+			rendered = append(rendered, r.await(segment, pending[i], start)...)
+			continue
+		}
 		switch segment {
 		case "user":
 			if userLabel := resolveUserSegmentLabel(ctx.WorkDir); userLabel != "" {
-				rendered = append(rendered, newSegment("user", labelWithOptionalIcon(userPromptIcon, userLabel), palette))
-			}
-		case "git":
-			if branchLabel := resolveGitSegmentLabel(ctx.WorkDir); branchLabel != "" {
-				rendered = append(rendered, newSegment("git", labelWithOptionalIcon(gitPromptIcon, branchLabel), palette))
+				rendered = append(rendered, r.segment("user", userIcon, "{label}", "label", userLabel))
 			}
 		case "path":
-			wd := ctx.WorkDir
-			if wd == "" {
-				wd, _ = os.Getwd()
-			}
-			rendered = append(rendered, renderPathSegments(wd, palette)...)
+			rendered = append(rendered, r.pathSegments(wd)...)
 		case "time":
-			rendered = append(rendered, newSegment("time", labelWithOptionalIcon(timePromptIcon, time.Now().Format("3:04 PM")), palette))
+			now := time.Now().Format(r.styles["time"].TimeFormat)
+			rendered = append(rendered, r.segment("time", timeIcon, "{time}", "time", now))
 		case "exit_code":
 			if ctx.LastExitCode != 0 {
-				suffix := "errors"
+				errors := "errors"
 				if ctx.LastExitCode == 1 {
-					suffix = "error"
+					errors = "error"
 				}
-				rendered = append(rendered, newSegment("exit_code", labelWithOptionalIcon(errorPromptIcon, fmt.Sprintf("%d %s", ctx.LastExitCode, suffix)), palette))
+				rendered = append(rendered, r.segment("exit_code", errorIcon, "{code}", "code", strconv.Itoa(ctx.LastExitCode), "errors", errors))
+			}
+		case "duration":
+			if threshold := time.Duration(r.styles["duration"].Threshold) * time.Millisecond; ctx.Duration > 0 && ctx.Duration >= threshold {
+				rendered = append(rendered, r.segment("duration", clockIcon, "{duration}", "duration", formatDuration(ctx.Duration)))
 			}
 		}
 	}
+	return rendered
+}
+
+func (r *Renderer) symbolSegment(unicodeOK bool) renderSegment {
+	symbol := r.opts.Symbol
 	if symbol == "" {
 		symbol = ">"
 	}
 	if !unicodeOK && !isASCII(symbol) {
 		symbol = ">"
 	}
-	symbolSegment := newSegment("symbol", symbol, palette)
-
-	if len(rendered) == 0 {
-		return renderWithArrows([]renderSegment{symbolSegment}, unicodeOK)
-	}
-
-	badges := strings.TrimRight(renderWithArrows(rendered, unicodeOK), " ")
-	promptSymbol := strings.TrimLeft(renderWithArrows([]renderSegment{symbolSegment}, unicodeOK), " ")
-
-	return badges + "\n" + promptLinePrefix + promptSymbol
+	return r.style("symbol", newSegment("symbol", symbol, r.palette))
 }
 
 func renderPathParts(wd string) []string {
-	drivePromptIcon := promptIcon(driveIcon)
-	folderPromptIcon := promptIcon(folderIcon)
+	return pathParts(wd, promptIcon(driveIcon), promptIcon(folderIcon), func(name string) string { return name })
+}
 
+// pathParts splits wd into breadcrumbs, passing each folder name through
+// label before the icon is added.
+func pathParts(wd, drivePromptIcon, folderPromptIcon string, label func(string) string) []string {
 	if wd == "" {
 		root := folderPromptIcon
 		if root == "" {
@@ -147,7 +342,7 @@ func renderPathParts(wd string) []string {
 	}
 
 	for _, part := range parts {
-		crumbs = append(crumbs, labelWithOptionalIcon(folderPromptIcon, part))
+		crumbs = append(crumbs, labelWithOptionalIcon(folderPromptIcon, label(part)))
 		if len(crumbs) >= maxPathBreadcrumbs {
 			break
 		}
@@ -164,13 +359,28 @@ func renderPathParts(wd string) []string {
 	return crumbs
 }
 
-func renderPathSegments(wd string, palette map[string]string) []renderSegment {
-	parts := renderPathParts(wd)
+// pathSegments renders wd as breadcrumbs styled by [segment.path].
+func (r *Renderer) pathSegments(wd string) []renderSegment {
+	style := r.styles["path"]
+	format := style.Format
+	if format == "" {
+		format = "{name}"
+	}
+	parts := pathParts(wd, promptIcon(driveIcon), r.icon("path", folderIcon), func(name string) string {
+		return truncateLabel(strings.ReplaceAll(format, "{name}", name), style.MaxLength)
+	})
+	segments := renderPathSegments(parts, r.palette, r.path)
+	for i := range segments {
+		segments[i] = r.style("path", segments[i])
+	}
+	return segments
+}
+
+func renderPathSegments(parts []string, palette map[string]string, colors pathColors) []renderSegment {
 	segments := make([]renderSegment, 0, len(parts))
-	pathColors := pathGradient(palette)
 	for i, part := range parts {
 		segment := newSegment("path", part, palette)
-		segment.bg = pathColors[i%len(pathColors)]
+		segment.bg = colors.pick(i, len(parts), part)
 		segments = append(segments, segment)
 	}
 
@@ -181,17 +391,49 @@ func resolveUserSegmentLabel(workDir string) string {
 	envLabel := resolveActiveEnvLabel()
 
 	if envLabel != "" {
-		return truncateLabel(strings.ToUpper(envLabel), 6)
+		return strings.ToUpper(envLabel)
 	}
-	return truncateLabel(resolveSystemIdentityLabel(), 6)
+	return resolveSystemIdentityLabel()
 }
 
+// truncateLabel shortens label to maxLen characters followed by "...". A
+// maxLen of zero means no limit. Escape sequences are kept and not counted.
 func truncateLabel(label string, maxLen int) string {
 	label = strings.TrimSpace(label)
-	if len(label) <= maxLen {
+	if maxLen <= 0 {
 		return label
 	}
-	return label[:maxLen] + "..."
+	var out strings.Builder
+	n := 0
+	for i := 0; i < len(label); {
+		if end := escapeEnd(label, i); end > i {
+			out.WriteString(label[i:end])
+			i = end
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(label[i:])
+		if n == maxLen {
+			return out.String() + "..."
+		}
+		out.WriteRune(r)
+		n++
+		i += size
+	}
+	return label
+}
+
+// escapeEnd returns the end of the CSI escape sequence starting at s[i], or
+// i when there is none.
+func escapeEnd(s string, i int) int {
+	if !strings.HasPrefix(s[i:], "\x1b[") {
+		return i
+	}
+	for j := i + 2; j < len(s); j++ {
+		if s[j] >= 0x40 && s[j] <= 0x7e {
+			return j + 1
+		}
+	}
+	return i
 }
 
 func resolveActiveEnvLabel() string {
@@ -239,34 +481,6 @@ func resolveGitBranchLabel(workDir string) string {
 	return branch
 }
 
-func resolveGitSegmentLabel(workDir string) string {
-	dir := strings.TrimSpace(workDir)
-	if dir == "" {
-		if wd, err := os.Getwd(); err == nil {
-			dir = wd
-		}
-	}
-	if dir == "" {
-		return ""
-	}
-
-	branch, err := resolveGitBranchForDir(dir)
-	if err != nil {
-		return ""
-	}
-	branch = strings.TrimSpace(branch)
-	if branch == "" || strings.EqualFold(branch, "HEAD") {
-		return ""
-	}
-
-	dirty := ""
-	if dirtyResult, err := resolveGitDirtyForDir(dir); err == nil && dirtyResult {
-		dirty = " *"
-	}
-
-	return branch + dirty
-}
-
 func resolveSystemIdentityLabel() string {
 	username := ""
 	if u, err := resolveCurrentUser(); err == nil && u != nil {
@@ -369,7 +583,7 @@ func pathGradient(palette map[string]string) []string {
 		"#6200ea", "#00b0ff", "#00bfa5", "#ff0097", "#aa00ff",
 		"#00c853", "#ff6d00", "#304ffe", "#00e5ff", "#d500f9",
 		"#64dd17", "#ffab00", "#2962ff", "#1de9b6", "#e91e63",
-		"#76ff03", "#ff3d00", "#3d5afe", "#00b8d4",
+		"#76ff03", "#ff3d00", "#3d5afe", "#00b8d4", "#c51162",
 	}
 
 	base := strings.TrimSpace(palette["path_bg"])
@@ -377,7 +591,7 @@ func pathGradient(palette map[string]string) []string {
 		base = ""
 	}
 	if base == "" {
-		return shuffledColors(defaultColors)
+		return defaultColors
 	}
 
 	colors := make([]string, 0, defaultGradientSteps)
@@ -392,15 +606,7 @@ func pathGradient(palette map[string]string) []string {
 		}
 	}
 
-	return shuffledColors(colors)
-}
-
-func shuffledColors(colors []string) []string {
-	shuffled := append([]string(nil), colors...)
-	rand.Shuffle(len(shuffled), func(i, j int) {
-		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
-	})
-	return shuffled
+	return colors
 }
 
 func newSegment(name, text string, palette map[string]string) renderSegment {
@@ -419,7 +625,7 @@ func newSegment(name, text string, palette map[string]string) renderSegment {
 	}
 }
 
-func renderWithArrows(segments []renderSegment, unicodeOK bool) string {
+func renderWithArrows(segments []renderSegment, unicodeOK bool, mode colorMode) string {
 	var out strings.Builder
 	separator := segmentSeparator
 	if !unicodeOK {
@@ -436,7 +642,7 @@ func renderWithArrows(segments []renderSegment, unicodeOK bool) string {
 			text = " " + text + " "
 		}
 
-		if start := ansiSeq(segment.fg, segment.bg); start != "" {
+		if start := mode.seq(segment.fg, segment.bg); start != "" {
 			out.WriteString("\x1b[1m")
 			out.WriteString(start)
 			out.WriteString(text)
@@ -448,14 +654,14 @@ func renderWithArrows(segments []renderSegment, unicodeOK bool) string {
 		}
 
 		if segment.bg != "" {
-			arrowStyle := ansiSeq(segment.bg, nextBG)
+			arrowStyle := mode.seq(segment.bg, nextBG)
 			if arrowStyle != "" {
 				out.WriteString(arrowStyle)
 				out.WriteString(separator)
 				out.WriteString("\x1b[0m")
 			} else {
 				if nextBG == "" {
-					arrowStyle := ansiRGB("38", segment.bg)
+					arrowStyle := mode.sgr("38", segment.bg)
 					if arrowStyle != "" {
 						out.WriteString(arrowStyle)
 						out.WriteString(separator)
@@ -564,17 +770,6 @@ func isASCII(s string) bool {
 	return true
 }
 
-func ansiSeq(fg, bg string) string {
-	var seq strings.Builder
-	if fgCode := ansiRGB("38", fg); fgCode != "" {
-		seq.WriteString(fgCode)
-	}
-	if bgCode := ansiRGB("48", bg); bgCode != "" {
-		seq.WriteString(bgCode)
-	}
-	return seq.String()
-}
-
 func ansiRGB(prefix, color string) string {
 	if !strings.HasPrefix(color, "#") || len(color) != 7 {
 		return ""
@@ -8,6 +8,7 @@ import (
 
 func TestRenderAppliesPaletteBadges(t *testing.T) {
 	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("COLORTERM", "truecolor")
 	t.Setenv("VOID_PROMPT_UNICODE", "1")
 
 	palette := map[string]string{
@@ -99,7 +100,7 @@ func TestRenderPathSegmentsUsesSameForegroundColor(t *testing.T) {
 		"path_fg": "#ffd166",
 		"path_bg": "#1f2937",
 	}
-	segments := renderPathSegments("/Users/Asus/Desktop", palette)
+	segments := renderPathSegments(renderPathParts("/Users/Asus/Desktop"), palette, newPathColors("", palette))
 	if len(segments) < 3 {
 		t.Fatalf("expected multiple path segments, got %d", len(segments))
 	}
@@ -267,6 +268,7 @@ func TestResolveUserSegmentLabelShowsDirtyIndicator(t *testing.T) {
 
 func TestRenderUserSegmentForcesWhiteText(t *testing.T) {
 	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("COLORTERM", "truecolor")
 	t.Setenv("VOID_PROMPT_UNICODE", "1")
 	t.Setenv("VOID_ACTIVE_LABEL", "dev")
 
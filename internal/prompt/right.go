@@ -0,0 +1,82 @@
+package prompt
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/void-shell/void/internal/textwidth"
+)
+
+const (
+	rightSeparator      = "\ue0b2"
+	rightSeparatorASCII = "<"
+)
+
+// terminalColumns returns the width of the terminal the prompt is drawn
+// in, or 0 when it is unknown. Shell hooks capture stdout, so stderr and
+// COLUMNS are consulted too.
+var terminalColumns = func() int {
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if w, _, err := term.GetSize(int(f.Fd())); err == nil && w > 0 {
+			return w
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// alignRight pads left so right ends one column short of the terminal
+// edge; filling the last column would make some terminals wrap. right is
+// dropped when both do not fit, and follows left after a space when the
+// width is unknown.
+func alignRight(left, right string, columns int) string {
+	if columns <= 0 {
+		if left == "" {
+			return right
+		}
+		return left + " " + right
+	}
+	gap := columns - 1 - textwidth.String(left) - textwidth.String(right)
+	minGap := 0
+	if left != "" {
+		minGap = 1
+	}
+	if gap < minGap {
+		return left
+	}
+	return left + strings.Repeat(" ", gap) + right
+}
+
+// renderRightSegments draws segments with separators pointing left, the
+// mirror image of renderWithArrows.
+func renderRightSegments(segments []renderSegment, unicodeOK bool, mode colorMode) string {
+	var out strings.Builder
+	separator := rightSeparator
+	if !unicodeOK {
+		separator = rightSeparatorASCII
+	}
+	prevBG := ""
+	for i, segment := range segments {
+		text := segment.text
+		if segment.bg != "" {
+			out.WriteString(mode.seq(segment.bg, prevBG))
+			out.WriteString(separator)
+			out.WriteString("\x1b[0m")
+			text = " " + text + " "
+		} else if i > 0 {
+			out.WriteByte(' ')
+		}
+
+		out.WriteString("\x1b[1m")
+		out.WriteString(mode.seq(segment.fg, segment.bg))
+		out.WriteString(text)
+		out.WriteString("\x1b[0m")
+		prevBG = segment.bg
+	}
+	return out.String()
+}
@@ -0,0 +1,56 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/void-shell/void/internal/textwidth"
+)
+
+func TestRenderAlignsRightSegments(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	t.Setenv("VOID_ACTIVE_LABEL", "界📂")
+	r := New(Options{
+		Segments:      []string{"user"},
+		RightSegments: []string{"exit_code"},
+		Palette:       map[string]string{"user_bg": "#6200ea", "exit_code_bg": "#d50000"},
+	})
+	out := r.Render(Context{LastExitCode: 2, Columns: 60})
+	line, _, _ := strings.Cut(out, "\n")
+	if got := textwidth.String(line); got != 59 {
+		t.Fatalf("expected the badge line to end one column short of 60, got %d in %q", got, line)
+	}
+	if !strings.Contains(line, "界📂") || !strings.Contains(line, rightSeparator) || !strings.HasSuffix(line, "2 errors \x1b[0m") {
+		t.Fatalf("expected exit_code at the right edge, got %q", line)
+	}
+
+	line, _, _ = strings.Cut(r.Render(Context{LastExitCode: 2, Columns: 12}), "\n")
+	if strings.Contains(line, "errors") {
+		t.Fatalf("expected right segments to be dropped when they do not fit, got %q", line)
+	}
+}
+
+func TestAlignRight(t *testing.T) {
+	if got := alignRight("ab", "cd", 10); got != "ab     cd" {
+		t.Fatalf("unexpected alignment %q", got)
+	}
+	if got := alignRight("", "⚠️", 5); got != "  ⚠️" {
+		t.Fatalf("expected emoji to count as two columns, got %q", got)
+	}
+	if got := alignRight("ab", "cd", 0); got != "ab cd" {
+		t.Fatalf("expected an unknown width to keep both sides, got %q", got)
+	}
+}
+
+func TestTransientPrompt(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	if got := New(Options{Symbol: "❯"}).Transient(); got != "" {
+		t.Fatalf("expected no transient prompt by default, got %q", got)
+	}
+	got := New(Options{Symbol: "❯", Transient: true}).Transient()
+	if !strings.Contains(got, "❯") || strings.Contains(got, "\n") {
+		t.Fatalf("expected a one-line transient prompt, got %q", got)
+	}
+}
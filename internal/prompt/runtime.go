@@ -0,0 +1,316 @@
+package prompt
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runtimeSpec describes a language segment: the files that turn it on, the
+// version a project pins, and the command that reports the installed one.
+type runtimeSpec struct {
+	icon    string
+	markers []string
+	// pinned returns the version the project rooted at root asks for, or "".
+	pinned func(root string) string
+	// command prints the installed version somewhere in its output.
+	command []string
+}
+
+var runtimes = map[string]runtimeSpec{
+	"go": {
+		icon:    "go",
+		markers: []string{"go.mod", "go.work"},
+		pinned:  goModVersion,
+		command: []string{"go", "version"},
+	},
+	"node": {
+		icon:    "node",
+		markers: []string{"package.json", ".nvmrc", ".node-version"},
+		pinned:  firstLineOf(".nvmrc", ".node-version"),
+		command: []string{"node", "--version"},
+	},
+	"python": {
+		icon:    "py",
+		markers: []string{"pyproject.toml", ".python-version", "requirements.txt", "setup.py", "Pipfile"},
+		pinned:  pythonVersion,
+		command: []string{pythonCommand(), "--version"},
+	},
+	"rust": {
+		icon:    "rust",
+		markers: []string{"Cargo.toml", "rust-toolchain", "rust-toolchain.toml"},
+		pinned:  rustToolchainVersion,
+		command: []string{"rustc", "--version"},
+	},
+	"java": {
+		icon:    "java",
+		markers: []string{"pom.xml", "build.gradle", "build.gradle.kts", ".java-version"},
+		pinned:  firstLineOf(".java-version"),
+		command: []string{"java", "-version"},
+	},
+}
+
+func pythonCommand() string {
+	if runtime.GOOS == "windows" {
+		return "python"
+	}
+	return "python3"
+}
+
+// projectRoot returns the nearest directory at or above dir that contains
+// one of markers.
+func projectRoot(dir string, markers []string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, m := range markers {
+			if _, err := os.Stat(filepath.Join(dir, m)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// runtimeStamp identifies the project spec applies to in dir and the tool
+// that would report its version, changing when a marker file is edited or
+// the tool is replaced. Since it covers everything the version depends on,
+// a cached version with the same stamp never needs refreshing. ok is false
+// when the segment does not apply.
+func runtimeStamp(name string, spec runtimeSpec, dir string) (string, bool) {
+	root := projectRoot(dir, spec.markers)
+	if root == "" && !(name == "python" && os.Getenv("VIRTUAL_ENV") != "") {
+		return "", false
+	}
+	var b strings.Builder
+	b.WriteString(root)
+	for _, m := range spec.markers {
+		if root == "" {
+			break
+		}
+		if fi, err := os.Stat(filepath.Join(root, m)); err == nil {
+			fmt.Fprintf(&b, "|%s:%d", m, fi.ModTime().UnixNano())
+		}
+	}
+	if name == "python" {
+		b.WriteString("|" + os.Getenv("VIRTUAL_ENV"))
+	}
+	if path, err := exec.LookPath(spec.command[0]); err == nil {
+		if fi, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "|%s:%d:%d", path, fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	return b.String(), true
+}
+
+// runtimeVersion returns the version pinned by the project in dir, or else
+// the version of the installed tool.
+func runtimeVersion(spec runtimeSpec, dir string) string {
+	if v := spec.pinned(projectRoot(dir, spec.markers)); v != "" {
+		return v
+	}
+	return versions.lookup(spec.command)
+}
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(?:\.\d+)?`)
+
+// parseVersion picks the first version number out of a tool's output.
+func parseVersion(out string) string {
+	return versionPattern.FindString(out)
+}
+
+func goModVersion(root string) string {
+	for _, name := range []string{"go.mod", "go.work"} {
+		f, err := os.Open(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "go "); ok {
+				f.Close()
+				return strings.TrimSpace(v)
+			}
+		}
+		f.Close()
+	}
+	return ""
+}
+
+// firstLineOf returns a pinned func reading the first of files that exists,
+// such as .nvmrc. Aliases like "lts/*" are not versions and are ignored.
+func firstLineOf(files ...string) func(string) string {
+	return func(root string) string {
+		for _, name := range files {
+			data, err := os.ReadFile(filepath.Join(root, name))
+			if err != nil {
+				continue
+			}
+			line, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+			return parseVersion(line)
+		}
+		return ""
+	}
+}
+
+// pythonVersion prefers .python-version, then the active virtualenv. root is
+// empty when only the virtualenv turned the segment on.
+func pythonVersion(root string) string {
+	if root != "" {
+		if v := firstLineOf(".python-version")(root); v != "" {
+			return v
+		}
+	}
+	if venv := os.Getenv("VIRTUAL_ENV"); venv != "" {
+		data, err := os.ReadFile(filepath.Join(venv, "pyvenv.cfg"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				key, value, ok := strings.Cut(line, "=")
+				if ok && (strings.TrimSpace(key) == "version" || strings.TrimSpace(key) == "version_info") {
+					return parseVersion(value)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func rustToolchainVersion(root string) string {
+	for _, name := range []string{"rust-toolchain.toml", "rust-toolchain"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if value, ok := strings.CutPrefix(line, "channel"); ok {
+				line = strings.Trim(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "=")), `"'`)
+			}
+			if v := parseVersion(line); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// versionCacheFile holds installed tool versions between shell sessions.
+var versionCacheFile = func() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".void", "cache", "versions.json")
+}
+
+// runVersionCommand runs a tool's version command and returns its output.
+var runVersionCommand = func(path string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Some tools, java among them, print their version on stderr.
+	out, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	return string(out), err
+}
+
+// versionEntry is the cached version of the executable at a path, valid
+// while the file keeps its size and modification time.
+type versionEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	Version string `json:"version"`
+}
+
+type versionCache struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]versionEntry
+}
+
+var versions = &versionCache{}
+
+// lookup returns the version printed by command, running it only when the
+// executable changed since the cached answer.
+func (c *versionCache) lookup(command []string) string {
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return ""
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	c.mu.Lock()
+	c.load()
+	e, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && e.Size == fi.Size() && e.ModTime == fi.ModTime().UnixNano() {
+		return e.Version
+	}
+
+	out, err := runVersionCommand(path, command[1:]...)
+	if err != nil {
+		return ""
+	}
+	e = versionEntry{Size: fi.Size(), ModTime: fi.ModTime().UnixNano(), Version: parseVersion(out)}
+	c.mu.Lock()
+	c.entries[path] = e
+	c.save()
+	c.mu.Unlock()
+	return e.Version
+}
+
+// load reads the cache file once. c.mu must be held.
+func (c *versionCache) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.entries = map[string]versionEntry{}
+	if path := versionCacheFile(); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &c.entries)
+		}
+	}
+}
+
+// save writes the cache file, ignoring errors: a lost cache only costs a
+// command run. c.mu must be held.
+func (c *versionCache) save() {
+	path := versionCacheFile()
+	if path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	// Each writer gets its own temporary file, so prompts in other shells
+	// never rename a half-written one into place.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil && cerr == nil {
+		os.Rename(tmp.Name(), path)
+	}
+}
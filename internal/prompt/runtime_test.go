@@ -0,0 +1,171 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeVersions points the version cache at a temporary file and counts the
+// version commands run.
+func fakeVersions(t *testing.T, out string) (*int, string) {
+	cacheFile := filepath.Join(t.TempDir(), "versions.json")
+	origFile, origRun, origCache := versionCacheFile, runVersionCommand, versions
+	t.Cleanup(func() { versionCacheFile, runVersionCommand, versions = origFile, origRun, origCache })
+	runs := 0
+	versionCacheFile = func() string { return cacheFile }
+	runVersionCommand = func(string, ...string) (string, error) {
+		runs++
+		return out, nil
+	}
+	versions = &versionCache{}
+	return &runs, cacheFile
+}
+
+func TestPinnedRuntimeVersions(t *testing.T) {
+	t.Setenv("VIRTUAL_ENV", "")
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/x\n\ngo 1.25.1\n")
+	writeFile(t, filepath.Join(root, ".nvmrc"), "v20.11.0\n")
+	writeFile(t, filepath.Join(root, ".python-version"), "3.12\n")
+	writeFile(t, filepath.Join(root, "rust-toolchain.toml"), "[toolchain]\nchannel = \"1.79.0\"\n")
+	sub := filepath.Join(root, "cmd", "x")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"go": "1.25.1", "node": "20.11.0", "python": "3.12", "rust": "1.79.0"} {
+		if got := runtimeVersion(runtimes[name], sub); got != want {
+			t.Fatalf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestRuntimeSegmentNeedsMarker(t *testing.T) {
+	t.Setenv("VIRTUAL_ENV", "")
+	dir := t.TempDir()
+	if _, ok := runtimeStamp("go", runtimes["go"], dir); ok {
+		t.Fatalf("expected no go segment outside a module")
+	}
+
+	venv := t.TempDir()
+	writeFile(t, filepath.Join(venv, "pyvenv.cfg"), "home = /usr/bin\nversion = 3.11.4\n")
+	t.Setenv("VIRTUAL_ENV", venv)
+	if _, ok := runtimeStamp("python", runtimes["python"], dir); !ok {
+		t.Fatalf("expected an active virtualenv to show the python segment")
+	}
+	if got := runtimeVersion(runtimes["python"], dir); got != "3.11.4" {
+		t.Fatalf("expected the virtualenv version, got %q", got)
+	}
+}
+
+func TestVersionCacheRunsCommandOncePerExecutable(t *testing.T) {
+	bin := t.TempDir()
+	writeFile(t, filepath.Join(bin, "node"), "")
+	if err := os.Chmod(filepath.Join(bin, "node"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	runs, cacheFile := fakeVersions(t, "v22.3.0\n")
+
+	for range 2 {
+		if got := versions.lookup([]string{"node", "--version"}); got != "22.3.0" {
+			t.Fatalf("expected 22.3.0, got %q", got)
+		}
+	}
+	// A new session reads the answer from disk.
+	versions = &versionCache{}
+	if got := versions.lookup([]string{"node", "--version"}); got != "22.3.0" || *runs != 1 {
+		t.Fatalf("expected one command run, got %d runs and %q", *runs, got)
+	}
+	if _, err := os.Stat(cacheFile); err != nil {
+		t.Fatalf("expected the cache file to be written: %v", err)
+	}
+
+	// Upgrading the tool changes the executable and invalidates the entry.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(bin, "node"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	versions.lookup([]string{"node", "--version"})
+	if *runs != 2 {
+		t.Fatalf("expected a changed executable to be run again, got %d runs", *runs)
+	}
+}
+
+func TestRenderRuntimeSegment(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module x\n\ngo 1.24\n")
+
+	r := New(Options{Segments: []string{"go", "node"}, Timeout: time.Second})
+	out := r.Render(Context{WorkDir: dir})
+	r.Wait()
+	if !strings.Contains(out, "go v1.24") {
+		t.Fatalf("expected the go version, got %q", out)
+	}
+	if strings.Contains(out, "node") {
+		t.Fatalf("expected no node segment without package.json, got %q", out)
+	}
+}
+
+func TestOneShotRuntimeSegmentRunsVersionCommandOnce(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	bin := t.TempDir()
+	node := filepath.Join(bin, "node")
+	writeFile(t, node, "")
+	if err := os.Chmod(node, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	fakeVersions(t, "")
+	var runs atomic.Int32
+	// Slower than the prompt timeout, like a cold java -version.
+	runVersionCommand = func(string, ...string) (string, error) {
+		runs.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return "v22.3.0\n", nil
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), "{}")
+	opts := Options{Segments: []string{"node"}, Timeout: 20 * time.Millisecond, CacheFile: filepath.Join(t.TempDir(), "segments.json")}
+	for i := range 3 {
+		versions = &versionCache{}
+		r := New(opts)
+		if out := r.Render(Context{WorkDir: dir}); !strings.Contains(out, "v22.3.0") {
+			t.Fatalf("prompt %d: expected the node version, got %q", i, out)
+		}
+		r.Wait()
+	}
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("expected node --version to run once, ran %d times", n)
+	}
+
+	// Replacing the tool changes the stamp, so the next prompt asks again.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(node, later, later); err != nil {
+		t.Fatal(err)
+	}
+	r := New(opts)
+	r.Render(Context{WorkDir: dir})
+	r.Wait()
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("expected an upgraded tool to be asked again, ran %d times", n)
+	}
+}
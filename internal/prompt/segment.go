@@ -0,0 +1,144 @@
+package prompt
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// segmentIcons are the built-in icons of segments that may be drawn as a
+// placeholder.
+var segmentIcons = map[string]string{
+	"git": gitIcon,
+}
+
+// segment renders the segment called name from its format template, which
+// defaults to format. vars are placeholder names and values in pairs, so
+// "code", "1" replaces {code} with 1.
+func (r *Renderer) segment(name, icon, format string, vars ...string) renderSegment {
+	style := r.styles[name]
+	if style.Format != "" {
+		format = style.Format
+	}
+	pairs := make([]string, 0, len(vars))
+	for i := 0; i+1 < len(vars); i += 2 {
+		pairs = append(pairs, "{"+vars[i]+"}", vars[i+1])
+	}
+	text := truncateLabel(strings.NewReplacer(pairs...).Replace(format), style.MaxLength)
+	return r.style(name, newSegment(name, labelWithOptionalIcon(r.icon(name, icon), text), r.palette))
+}
+
+// icon returns the icon configured for segment name, or fallback.
+func (r *Renderer) icon(name, fallback string) string {
+	switch icon := r.styles[name].Icon; icon {
+	case "":
+		return promptIcon(fallback)
+	case "none":
+		return ""
+	default:
+		return promptIcon(icon)
+	}
+}
+
+// style applies the fg and bg set for segment name over the palette colours.
+func (r *Renderer) style(name string, seg renderSegment) renderSegment {
+	style := r.styles[name]
+	if style.FG != "" {
+		seg.fg = style.FG
+	}
+	if style.BG != "" {
+		seg.bg = style.BG
+	}
+	return seg
+}
+
+// visible evaluates a segment's "when" condition.
+func visible(when string, ctx Context) bool {
+	switch when {
+	case "never":
+		return false
+	case "error":
+		return ctx.LastExitCode != 0
+	case "success":
+		return ctx.LastExitCode == 0
+	}
+	if name, ok := strings.CutPrefix(when, "env:"); ok {
+		return os.Getenv(name) != ""
+	}
+	return true
+}
+
+// gitData reads the git state of dir. It runs in the background.
+func gitData(dir string) segmentData {
+	s, ok := resolveGitStatusForDir(dir)
+	if !ok {
+		return segmentData{}
+	}
+	return segmentData{Git: &s}
+}
+
+// gitSegments renders the git segment for s, which is nil outside a
+// repository.
+func (r *Renderer) gitSegments(s *gitStatus) []renderSegment {
+	if s == nil || s.Head == "" {
+		return nil
+	}
+	dirty := ""
+	if s.dirty() {
+		dirty = " *"
+	}
+	fg := r.style("git", newSegment("git", "", r.palette)).fg
+	status := r.gitIndicators(*s, fg)
+	if status != "" {
+		status = " " + status
+	}
+	return []renderSegment{r.segment("git", gitIcon, "{branch}{status}",
+		"branch", s.Head,
+		"dirty", dirty,
+		"status", status,
+		"ahead", strconv.Itoa(s.Ahead),
+		"behind", strconv.Itoa(s.Behind),
+		"staged", strconv.Itoa(s.Staged),
+		"modified", strconv.Itoa(s.Modified),
+		"untracked", strconv.Itoa(s.Untracked),
+		"conflicted", strconv.Itoa(s.Conflicted),
+		"stash", strconv.Itoa(s.Stash),
+		"operation", s.Operation,
+	)}
+}
+
+// runtimeSegments renders a language version segment.
+func (r *Renderer) runtimeSegments(name string, spec runtimeSpec, version string) []renderSegment {
+	if version == "" {
+		return nil
+	}
+	return []renderSegment{r.segment(name, spec.icon, "v{version}", "version", version)}
+}
+
+// await waits for a slow segment until its timeout, counted from start. A
+// segment that has never finished in time is drawn as a placeholder.
+func (r *Renderer) await(name string, p *pendingSegment, start time.Time) []renderSegment {
+	timeout := r.opts.Timeout
+	if ms := r.styles[name].Timeout; ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	if timeout <= 0 {
+		timeout = defaultSegmentTimeout
+	}
+	if data, ok := p.wait(start.Add(timeout)); ok {
+		if spec, ok := runtimes[name]; ok {
+			return r.runtimeSegments(name, spec, data.Version)
+		}
+		return r.gitSegments(data.Git)
+	}
+	placeholder := "…"
+	if !supportsUnicodePrompt() {
+		placeholder = "..."
+	}
+	icon := segmentIcons[name]
+	if spec, ok := runtimes[name]; ok {
+		icon = spec.icon
+	}
+	return []renderSegment{r.style(name, newSegment(name, labelWithOptionalIcon(r.icon(name, icon), placeholder), r.palette))}
+}
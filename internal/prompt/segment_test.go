@@ -0,0 +1,83 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/void-shell/void/internal/config"
+)
+
+func TestSegmentFormatIconAndLength(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "1")
+	t.Setenv("VOID_ACTIVE_LABEL", "production")
+
+	r := New(Options{
+		Segments: []string{"user", "exit_code"},
+		Segment: map[string]config.SegmentConfig{
+			"user":      {Format: "[{label}]", Icon: "none", MaxLength: 20},
+			"exit_code": {Format: "exit {code}", Icon: "!"},
+		},
+	})
+	out := r.Render(Context{LastExitCode: 3})
+	if !strings.Contains(out, "[PRODUCTION]") || strings.Contains(out, userIcon+" ") {
+		t.Fatalf("expected formatted user label without icon, got %q", out)
+	}
+	if !strings.Contains(out, "! exit 3") || strings.Contains(out, "errors") {
+		t.Fatalf("expected custom exit wording and icon, got %q", out)
+	}
+}
+
+func TestSegmentDefaultsComeFromConfig(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "0")
+	t.Setenv("VOID_ACTIVE_LABEL", "production")
+
+	out := New(Options{Segments: []string{"user", "exit_code"}}).Render(Context{LastExitCode: 2})
+	if !strings.Contains(out, "PRODUC...") || !strings.Contains(out, "2 errors") {
+		t.Fatalf("expected default truncation and wording, got %q", out)
+	}
+}
+
+func TestSegmentWhenCondition(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("VOID_PROMPT_UNICODE", "0")
+	t.Setenv("VOID_ACTIVE_LABEL", "dev")
+	t.Setenv("SHOW_TIME", "")
+
+	r := New(Options{
+		Segments: []string{"user", "time"},
+		Segment: map[string]config.SegmentConfig{
+			"user": {When: "error"},
+			"time": {When: "env:SHOW_TIME", TimeFormat: "(15)"},
+		},
+	})
+	if out := r.Render(Context{}); strings.Contains(out, "DEV") || strings.Contains(out, "(") {
+		t.Fatalf("expected both segments hidden, got %q", out)
+	}
+	t.Setenv("SHOW_TIME", "1")
+	if out := r.Render(Context{LastExitCode: 1}); !strings.Contains(out, "DEV") || !strings.Contains(out, "(") {
+		t.Fatalf("expected both segments shown, got %q", out)
+	}
+}
+
+func TestSegmentColorsOverridePalette(t *testing.T) {
+	r := New(Options{
+		Palette: map[string]string{"accent": "#112233", "time_bg": "#000000"},
+		Segment: map[string]config.SegmentConfig{"time": {FG: "white", BG: "accent", TimeFormat: "15"}},
+	})
+	seg := r.segment("time", timeIcon, "{time}", "time", "12")
+	if seg.fg != "#ffffff" || seg.bg != "#112233" {
+		t.Fatalf("expected segment colours to win over the palette, got %#v", seg)
+	}
+}
+
+func TestTruncateLabelSkipsEscapes(t *testing.T) {
+	got := truncateLabel("ab\x1b[38;2;0;255;0mcdef\x1b[39m", 3)
+	if got != "ab\x1b[38;2;0;255;0mc..." {
+		t.Fatalf("unexpected truncation %q", got)
+	}
+	if got := truncateLabel("héllo", 0); got != "héllo" {
+		t.Fatalf("expected no limit, got %q", got)
+	}
+}
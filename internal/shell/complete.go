@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/void-shell/void/internal/autocomplete"
+	"github.com/void-shell/void/internal/history"
+	"github.com/void-shell/void/internal/lineedit"
+)
+
+// newCompletionEngine builds the engine from the embedded specs plus the
+// user's specs in ~/.void/completions. Malformed user specs are reported
+// and skipped.
+func newCompletionEngine() *autocomplete.Engine {
+	engine := autocomplete.New()
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return engine
+	}
+	specs, err := autocomplete.LoadSpecDir(filepath.Join(home, ".void", "completions"))
+	engine.AddSpecs(specs...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "void: completions: %v\n", err)
+	}
+	return engine
+}
+
+// completeLine feeds the Tab menu from the autocomplete engine.
+func (a *App) completeLine(line string, pos int) lineedit.Completion {
+	result := a.complete.Complete(line, pos, a.completionHistory())
+	candidates := make([]lineedit.Candidate, 0, len(result.Suggestions))
+	for _, s := range result.Suggestions {
+		candidates = append(candidates, lineedit.Candidate{
+			Value:       s.Candidate,
+			Description: s.Description,
+			Partial:     s.Kind == autocomplete.KindDirectory,
+		})
+	}
+	return lineedit.Completion{Candidates: candidates, Start: result.Start, End: result.End}
+}
+
+// completionHistory hands the recorded command usage to the engine so Tab
+// completion and `void complete` rank candidates the same way.
+func (a *App) completionHistory() []autocomplete.Usage {
+	usage := a.history.Usage()
+	out := make([]autocomplete.Usage, 0, len(usage))
+	for _, u := range usage {
+		out = append(out, autocomplete.Usage{Command: u.Command, Count: u.Count, Last: u.Last})
+	}
+	return out
+}
+
+// searchHistory feeds Ctrl+R with fuzzy matches over the whole history,
+// including commands other sessions have run since.
+func (a *App) searchHistory(query string) []lineedit.SearchResult {
+	_ = a.history.Sync()
+	matches := history.FuzzySearch(a.history.Entries(), query)
+	results := make([]lineedit.SearchResult, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, lineedit.SearchResult{Text: m.Entry, Positions: m.Positions})
+	}
+	return results
+}
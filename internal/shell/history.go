@@ -0,0 +1,72 @@
+package shell
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/void-shell/void/internal/history"
+)
+
+// printHistory implements `void history [--cwd] [--failed] [--since v] [--grep text]`
+// and `void history sync`, which pulls other sessions' commands into Up/Down
+// recall.
+func (a *App) printHistory(args []string) int {
+	if err := a.history.Sync(); err != nil {
+		a.reportError(fmt.Sprintf("history: %v", err))
+		return 1
+	}
+	if len(args) == 1 && args[0] == "sync" {
+		fmt.Printf("history synced: %d commands\n", len(a.history.Entries()))
+		return 0
+	}
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cwd := fs.Bool("cwd", false, "only commands run in the current directory")
+	failed := fs.Bool("failed", false, "only commands that exited non-zero")
+	since := fs.String("since", "", "only commands run after a duration ago (2h, 3d) or a date (2024-05-01)")
+	grep := fs.String("grep", "", "only commands containing text")
+	if err := fs.Parse(args); err != nil {
+		a.reportError("usage: void history [--cwd] [--failed] [--since 2h|3d|2024-05-01] [--grep text]")
+		return 2
+	}
+
+	// Words after the flags extend --grep so unquoted phrases work.
+	filter := history.Filter{Failed: *failed, Grep: strings.TrimSpace(strings.Join(append([]string{*grep}, fs.Args()...), " "))}
+	if *cwd {
+		wd, err := os.Getwd()
+		if err != nil {
+			a.reportError(fmt.Sprintf("history: %v", err))
+			return 1
+		}
+		filter.Dir = wd
+	}
+	if *since != "" {
+		t, err := history.ParseSince(*since, time.Now())
+		if err != nil {
+			a.reportError(fmt.Sprintf("history: %v", err))
+			return 2
+		}
+		filter.Since = t
+	}
+
+	for _, e := range a.history.Select(filter) {
+		fmt.Println(formatHistoryEntry(e))
+	}
+	return 0
+}
+
+func formatHistoryEntry(e history.Entry) string {
+	when := "-"
+	if !e.Time.IsZero() {
+		when = e.Time.Local().Format("2006-01-02 15:04:05")
+	}
+	duration := "-"
+	if e.Duration > 0 {
+		duration = e.Duration.Round(time.Millisecond).String()
+	}
+	return fmt.Sprintf("%-19s  %3d  %8s  %s", when, e.ExitCode, duration, e.Command)
+}
@@ -1,8 +1,9 @@
 package shell
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,10 +11,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/void-shell/void/internal/autocomplete"
 	"github.com/void-shell/void/internal/config"
 	"github.com/void-shell/void/internal/history"
+	"github.com/void-shell/void/internal/lineedit"
 	"github.com/void-shell/void/internal/prompt"
 	"github.com/void-shell/void/internal/theme"
 )
@@ -23,8 +26,15 @@ type App struct {
 	configSrc string
 	lastCode  int
 	lastError string
-	history   *history.Store
-	complete  *autocomplete.Engine
+	// lastDuration is how long the last external command ran.
+	lastDuration time.Duration
+	history      *history.Store
+	complete     *autocomplete.Engine
+	editor       *lineedit.Editor
+	prompt       *prompt.Renderer
+
+	// configStamps are the config and preset files as of the last reload.
+	configStamps []fileStamp
 }
 
 func New(cfg config.Config, configSrc string) (*App, error) {
@@ -36,19 +46,46 @@ func New(cfg config.Config, configSrc string) (*App, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &App{cfg: merged, configSrc: configSrc, history: historyStore, complete: autocomplete.New()}, nil
+	redactor, err := history.NewRedactor(merged.History.Redact, merged.History.RedactMode, merged.History.DetectSecrets)
+	if err != nil {
+		return nil, err
+	}
+	historyStore.SetRedactor(redactor)
+	app := &App{
+		cfg:       merged,
+		configSrc: configSrc,
+		history:   historyStore,
+		complete:  newCompletionEngine(),
+		editor:    lineedit.New(os.Stdin, os.Stdout),
+		prompt:    prompt.New(prompt.OptionsFromConfig(merged)),
+	}
+	app.editor.Complete = app.completeLine
+	app.editor.Search = app.searchHistory
+	wd, _ := os.Getwd()
+	app.configStamps = stampFiles(app.configFiles(wd))
+	return app, nil
 }
 
 func (a *App) Run() error {
-	scanner := bufio.NewScanner(os.Stdin)
 	for {
 		wd, _ := os.Getwd()
-		fmt.Print(prompt.Render(a.cfg.Prompt.Segments, a.cfg.Prompt.Symbol, a.cfg.Palette, prompt.Context{LastExitCode: a.lastCode, WorkDir: wd}))
-		if !scanner.Scan() {
+		a.reloadIfChanged(wd)
+		ctx := prompt.Context{LastExitCode: a.lastCode, WorkDir: wd, Duration: a.lastDuration}
+		a.lastDuration = 0
+		promptText := a.prompt.Render(ctx)
+		a.editor.Transient = a.prompt.Transient()
+		input, err := a.editor.ReadLine(promptText, a.history.Entries())
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			continue
+		}
+		if err != nil {
 			_ = a.history.Save()
-			return nil
+			if err == io.EOF {
+				return nil
+			}
+			return err
 		}
-		line := strings.TrimSpace(scanner.Text())
+		line := strings.TrimSpace(input)
 		if line == "" {
 			continue
 		}
@@ -71,11 +108,32 @@ func (a *App) Run() error {
 			continue
 		}
 		expanded := a.expandAlias(line)
-		a.history.Add(expanded)
+		started := time.Now()
 		a.lastCode = a.runCommand(expanded)
+		a.lastDuration = time.Since(started)
+		fmt.Fprint(os.Stdout, a.prompt.Notification(prompt.Context{LastExitCode: a.lastCode, Duration: a.lastDuration}))
+		if a.cfg.History.IgnoreSpace && startsWithSpace(input) {
+			continue
+		}
+		entry := history.Entry{
+			Command:  expanded,
+			Time:     started,
+			Dir:      wd,
+			ExitCode: a.lastCode,
+			Duration: a.lastDuration,
+		}
+		if err := a.history.Record(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "void: history: %v\n", err)
+		}
 	}
 }
 
+// startsWithSpace reports whether the raw input line was typed with a leading
+// space, which keeps it out of history when ignore_space is set.
+func startsWithSpace(input string) bool {
+	return strings.HasPrefix(input, " ") || strings.HasPrefix(input, "\t")
+}
+
 func (a *App) expandAlias(line string) string {
 	fields := strings.Fields(line)
 	if len(fields) == 0 {
@@ -96,32 +154,25 @@ func (a *App) runMeta(line string) int {
 	}
 	switch fields[1] {
 	case "history":
-		for _, e := range a.history.Entries() {
-			fmt.Println(e)
-		}
-		return 0
+		return a.printHistory(fields[2:])
 	case "complete":
 		if len(fields) < 3 {
-			a.reportError("usage: void complete <prefix>")
+			a.reportError("usage: void complete <line>")
 			return 1
 		}
-		matches := a.complete.Complete(fields[2], a.history.Entries())
-		for _, m := range matches {
-			fmt.Println(m)
+		text := strings.TrimLeft(line[strings.Index(line, "complete")+len("complete"):], " \t")
+		result := a.complete.Complete(text, len([]rune(text)), a.completionHistory())
+		for _, s := range result.Suggestions {
+			fmt.Println(s.Candidate)
 		}
 		return 0
 	case "reload":
-		cfg, _, err := config.Load(a.configSrc)
-		if err != nil {
+		if err := a.reload(); err != nil {
 			a.reportError(fmt.Sprintf("reload failed: %v", err))
 			return 1
 		}
-		merged, err := theme.ApplyPreset(cfg)
-		if err != nil {
-			a.reportError(fmt.Sprintf("reload failed: %v", err))
-			return 1
-		}
-		a.cfg = merged
+		wd, _ := os.Getwd()
+		a.configStamps = stampFiles(a.configFiles(wd))
 		fmt.Println("configuration reloaded")
 		return 0
 	case "copy-error":
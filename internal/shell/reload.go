@@ -0,0 +1,103 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/void-shell/void/internal/config"
+	"github.com/void-shell/void/internal/history"
+	"github.com/void-shell/void/internal/prompt"
+	"github.com/void-shell/void/internal/theme"
+)
+
+// fileStamp is what hot-reload compares to notice that a file was created,
+// edited or removed.
+type fileStamp struct {
+	path    string
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// reload re-reads every config layer and the preset. Nothing changes unless
+// the whole configuration is valid. The prompt keeps its segment cache, and
+// the history file is reopened when its path or size changed.
+func (a *App) reload() error {
+	cfg, _, err := config.Load(a.configSrc)
+	if err != nil {
+		return err
+	}
+	merged, err := theme.ApplyPreset(cfg)
+	if err != nil {
+		return err
+	}
+	redactor, err := history.NewRedactor(merged.History.Redact, merged.History.RedactMode, merged.History.DetectSecrets)
+	if err != nil {
+		return err
+	}
+	store := a.history
+	if merged.History.Path != a.cfg.History.Path || merged.History.MaxSize != a.cfg.History.MaxSize {
+		if store, err = history.New(merged.History.Path, merged.History.MaxSize); err != nil {
+			return err
+		}
+	}
+	store.SetRedactor(redactor)
+	a.history = store
+	a.cfg = merged
+	if a.prompt == nil {
+		a.prompt = prompt.New(prompt.OptionsFromConfig(merged))
+	} else {
+		a.prompt = a.prompt.With(prompt.OptionsFromConfig(merged))
+	}
+	return nil
+}
+
+// reloadIfChanged reloads the configuration before a prompt when one of its
+// files changed since it was last read. An invalid edit is reported once and
+// the last good configuration stays active.
+func (a *App) reloadIfChanged(dir string) {
+	stamps := stampFiles(a.configFiles(dir))
+	if sameStamps(stamps, a.configStamps) {
+		return
+	}
+	a.configStamps = stamps
+	if err := a.reload(); err != nil {
+		a.reportError(fmt.Sprintf("config not reloaded, keeping the previous settings: %v", err))
+		return
+	}
+	// The preset may have changed along with the config.
+	a.configStamps = stampFiles(a.configFiles(dir))
+}
+
+// configFiles lists the config layer files and the preset file the active
+// configuration is read from.
+func (a *App) configFiles(dir string) []string {
+	files := config.LayerFiles(a.configSrc, dir)
+	return append(files, theme.Files(a.cfg.Preset)...)
+}
+
+func stampFiles(paths []string) []fileStamp {
+	stamps := make([]fileStamp, len(paths))
+	for i, path := range paths {
+		stamps[i].path = path
+		if fi, err := os.Stat(path); err == nil {
+			stamps[i].exists = true
+			stamps[i].modTime = fi.ModTime()
+			stamps[i].size = fi.Size()
+		}
+	}
+	return stamps
+}
+
+func sameStamps(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].path != b[i].path || a[i].exists != b[i].exists || a[i].size != b[i].size || !a[i].modTime.Equal(b[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/void-shell/void/internal/history"
+)
+
+func TestReloadIfChangedKeepsLastGoodConfig(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	path := filepath.Join(root, "config.toml")
+	write := func(content string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("preset = \"\"\n[alias]\nll = \"ls -l\"\n", start)
+
+	store, err := history.New(filepath.Join(root, "history"), 10)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	app := &App{configSrc: path, history: store}
+	if err := app.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	app.configStamps = stampFiles(app.configFiles(root))
+
+	app.reloadIfChanged(root)
+	if app.lastError != "" {
+		t.Fatalf("unchanged config should not reload: %q", app.lastError)
+	}
+
+	write("preset = \"\"\n[alias]\nll = \"ls -la\"\n", start.Add(time.Minute))
+	app.reloadIfChanged(root)
+	if got := app.cfg.Alias["ll"]; got != "ls -la" {
+		t.Fatalf("expected edited alias to be applied, got %q", got)
+	}
+
+	write("preset = \"\"\n[history]\nmax_size = 0\n[alias]\nll = \"ls\"\n", start.Add(2*time.Minute))
+	app.reloadIfChanged(root)
+	if got := app.cfg.Alias["ll"]; got != "ls -la" {
+		t.Fatalf("expected last good alias to be kept, got %q", got)
+	}
+	if !strings.Contains(app.lastError, "max_size") {
+		t.Fatalf("expected a warning about the invalid config, got %q", app.lastError)
+	}
+
+	app.clearError()
+	app.reloadIfChanged(root)
+	if app.lastError != "" {
+		t.Fatalf("an invalid config should only be reported once, got %q", app.lastError)
+	}
+}
+
+func TestReloadReopensHistoryWhenItsPathChanges(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	path := filepath.Join(root, "config.toml")
+	first, moved := filepath.Join(root, "history"), filepath.Join(root, "moved", "history")
+	if err := os.WriteFile(path, []byte("[history]\npath = '"+first+"'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := history.New(first, 10)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	app := &App{configSrc: path, history: store}
+	if err := app.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("[history]\npath = '"+moved+"'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if err := app.history.Add("echo moved"); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	data, err := os.ReadFile(moved)
+	if err != nil || !strings.Contains(string(data), "echo moved") {
+		t.Fatalf("expected the command in the new history file, got %q (%v)", data, err)
+	}
+}
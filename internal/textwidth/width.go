@@ -0,0 +1,121 @@
+// Package textwidth measures how many terminal columns text occupies.
+package textwidth
+
+import (
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+const (
+	zeroWidthJoiner = 0x200d
+	emojiVariation  = 0xfe0f
+)
+
+// String returns the number of columns s occupies, skipping ANSI CSI and OSC
+// escape sequences. Emoji sequences joined with U+200D, flags, skin tone
+// modifiers and text symbols followed by U+FE0F count as one wide glyph.
+func String(s string) int {
+	total := 0
+	last := 0 // width of the previous visible rune
+	joined := false
+	flag := false
+	esc := escNone
+	for _, r := range s {
+		if esc != escNone || r == 0x1b {
+			esc = esc.next(r)
+			continue
+		}
+		switch {
+		case r == zeroWidthJoiner:
+			joined = true
+			continue
+		case joined && Rune(r) > 0:
+			// The joined glyph is drawn inside the previous one.
+			joined = false
+			continue
+		case r == emojiVariation:
+			if last == 1 {
+				total++
+				last = 2
+			}
+			continue
+		case r >= 0x1f3fb && r <= 0x1f3ff && last == 2:
+			continue
+		case r >= 0x1f1e6 && r <= 0x1f1ff:
+			// Regional indicators pair up into one flag.
+			flag = !flag
+			if !flag {
+				continue
+			}
+			total += 2
+			last = 2
+			continue
+		}
+		joined, flag = false, false
+		if w := Rune(r); w > 0 {
+			total += w
+			last = w
+		}
+	}
+	return total
+}
+
+// Rune returns the number of columns r occupies on its own.
+func Rune(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r == zeroWidthJoiner:
+		return 0
+	case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), unicode.Is(unicode.Cf, r):
+		return 0
+	case r >= 0xfe00 && r <= 0xfe0f:
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// escState tracks an escape sequence being skipped.
+type escState int
+
+const (
+	escNone escState = iota
+	escStart
+	escCSI
+	escOSC
+	escOSCEnd // ESC seen inside an OSC, expecting the "\" of ST
+)
+
+func (e escState) next(r rune) escState {
+	switch e {
+	case escNone:
+		return escStart
+	case escStart:
+		switch r {
+		case '[':
+			return escCSI
+		case ']':
+			return escOSC
+		}
+		return escNone
+	case escCSI:
+		if r >= 0x40 && r <= 0x7e {
+			return escNone
+		}
+		return escCSI
+	case escOSC:
+		switch r {
+		case '\a':
+			return escNone
+		case 0x1b:
+			return escOSCEnd
+		}
+		return escOSC
+	}
+	return escNone
+}
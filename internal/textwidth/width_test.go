@@ -0,0 +1,25 @@
+package textwidth
+
+import "testing"
+
+func TestString(t *testing.T) {
+	cases := map[string]int{
+		"abc":                              3,
+		"\x1b[1m\x1b[38;2;1;2;3m> \x1b[0m": 2,
+		"\x1b]9;done\a>":                   1,
+		"\x1b]8;;https://x\x1b\\link":      4,
+		"界📂":                               4,
+		"é":                                1,
+		"⚠":                                1,
+		"⚠️":                               2,
+		"👩‍💻":                              2,
+		"👍🏽":                               2,
+		"🇳🇵🇺🇸":                             4,
+		"│ ⎇ main":                         8,
+	}
+	for s, want := range cases {
+		if got := String(s); got != want {
+			t.Fatalf("String(%q): expected %d, got %d", s, want, got)
+		}
+	}
+}
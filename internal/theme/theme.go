@@ -3,9 +3,13 @@ package theme
 import (
 	"embed"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/void-shell/void/internal/config"
 )
@@ -21,63 +25,282 @@ var presetMap = map[string]string{
 
 var executablePath = os.Executable
 
+// userThemesDir holds the user's own themes, one <name>.toml per theme.
+var userThemesDir = func() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".void", "themes")
+}
+
+// Theme is a prompt symbol, segment list and palette with everything it
+// extends already merged in.
+type Theme struct {
+	Name    string
+	Extends string
+	// Path is the file the theme was read from, empty for a theme built into
+	// the binary.
+	Path     string
+	Symbol   string
+	Segments []string
+	Palette  map[string]string
+}
+
+// themeFile is the layout of a theme TOML file.
+type themeFile struct {
+	Extends string `toml:"extends"`
+	Prompt  struct {
+		Symbol   string   `toml:"symbol"`
+		Segments []string `toml:"segments"`
+	} `toml:"prompt"`
+	Palette map[string]string `toml:"palette"`
+}
+
 func ApplyPreset(cfg config.Config) (config.Config, error) {
 	if cfg.Preset == "" {
 		return cfg, nil
 	}
-	file, ok := presetMap[cfg.Preset]
-	if !ok {
-		return cfg, fmt.Errorf("unknown preset: %s", cfg.Preset)
-	}
-	presetPath, err := resolvePresetPath(file)
+	t, err := Load(cfg.Preset)
 	if err != nil {
 		return cfg, err
 	}
-	themeCfg, _, err := config.Load(presetPath)
+	return t.Apply(cfg), nil
+}
+
+// Apply returns cfg with the theme's symbol, segments and palette colours
+// in place of its own. Segments listed in the user's own config win over
+// the theme's. cfg's palette map is not modified.
+func (t Theme) Apply(cfg config.Config) config.Config {
+	if t.Symbol != "" {
+		cfg.Prompt.Symbol = t.Symbol
+	}
+	if len(t.Segments) > 0 && !cfg.Explicit("prompt.segments") {
+		cfg.Prompt.Segments = t.Segments
+	}
+	palette := make(map[string]string, len(cfg.Palette)+len(t.Palette))
+	maps.Copy(palette, cfg.Palette)
+	maps.Copy(palette, t.Palette)
+	cfg.Palette = palette
+	return cfg
+}
+
+// Load reads the theme called name: a user theme from ~/.void/themes, or
+// else a built-in preset. A theme that extends its own name extends the
+// built-in preset it shadows.
+func Load(name string) (Theme, error) {
+	return load(name, false, nil)
+}
+
+func load(name string, builtinOnly bool, chain []string) (Theme, error) {
+	for _, seen := range chain {
+		if seen == name {
+			return Theme{}, fmt.Errorf("theme %q: extends cycle: %s", chain[0], strings.Join(append(chain, name), " -> "))
+		}
+	}
+	tf, path, err := readTheme(name, builtinOnly)
 	if err != nil {
-		return cfg, err
+		return Theme{}, err
+	}
+
+	t := Theme{Name: name, Extends: tf.Extends, Path: path, Palette: map[string]string{}}
+	if tf.Extends == name && builtinOnly {
+		return Theme{}, fmt.Errorf("theme %q extends itself", name)
+	}
+	if tf.Extends != "" {
+		next := chain
+		if tf.Extends != name {
+			next = append(chain, name)
+		}
+		base, err := load(tf.Extends, tf.Extends == name, next)
+		if err != nil {
+			return Theme{}, err
+		}
+		t.Symbol, t.Segments = base.Symbol, base.Segments
+		for k, v := range base.Palette {
+			t.Palette[k] = v
+		}
 	}
-	if themeCfg.Prompt.Symbol != "" {
-		cfg.Prompt.Symbol = themeCfg.Prompt.Symbol
+	if tf.Prompt.Symbol != "" {
+		t.Symbol = tf.Prompt.Symbol
 	}
-	if len(themeCfg.Prompt.Segments) > 0 {
-		cfg.Prompt.Segments = themeCfg.Prompt.Segments
+	if len(tf.Prompt.Segments) > 0 {
+		// Copied so callers cannot modify the cached theme.
+		t.Segments = append([]string(nil), tf.Prompt.Segments...)
 	}
-	for k, v := range themeCfg.Palette {
-		cfg.Palette[k] = v
+	for k, v := range tf.Palette {
+		t.Palette[k] = v
 	}
-	return cfg, nil
+	return t, nil
 }
 
-func resolvePresetPath(file string) (string, error) {
-	candidates := []string{filepath.Join("presets", file)}
-	if exe, err := executablePath(); err == nil && strings.TrimSpace(exe) != "" {
-		exeCandidate := filepath.Join(filepath.Dir(exe), "presets", file)
-		if exeCandidate != candidates[0] {
-			candidates = append(candidates, exeCandidate)
+// readTheme decodes the single file behind name without following extends.
+func readTheme(name string, builtinOnly bool) (themeFile, string, error) {
+	var tf themeFile
+	if strings.ContainsAny(name, `/\`) {
+		return tf, "", fmt.Errorf("invalid theme name %q", name)
+	}
+	path := themePath(name, builtinOnly)
+	if path == "" {
+		file, ok := presetMap[name]
+		if !ok {
+			return tf, "", fmt.Errorf("unknown preset: %s", name)
 		}
+		tf, err := decodeEmbedded(file)
+		return tf, "", err
 	}
+	tf, err := decodeCached(path)
+	return tf, path, err
+}
 
-	for _, candidate := range candidates {
+// themePath returns the file on disk that name is read from, or "" when it
+// is a preset built into the binary or does not exist.
+func themePath(name string, builtinOnly bool) string {
+	if dir := userThemesDir(); dir != "" && !builtinOnly {
+		path := filepath.Join(dir, name+".toml")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	file, ok := presetMap[name]
+	if !ok {
+		return ""
+	}
+	for _, candidate := range presetCandidates(file) {
 		if _, err := os.Stat(candidate); err == nil {
-			return candidate, nil
+			if abs, err := filepath.Abs(candidate); err == nil {
+				return abs
+			}
+			return candidate
 		}
 	}
+	return ""
+}
 
-	embeddedPath := "presets/" + file
-	data, err := presetFS.ReadFile(embeddedPath)
-	if err == nil {
-		tmpFile, err := os.CreateTemp("", "void-preset-*.toml")
+// Files returns the files on disk that theme name and the themes it extends
+// are read from, so callers can watch them for changes.
+func Files(name string) []string {
+	var files []string
+	builtinOnly := false
+	seen := map[string]bool{}
+	for name != "" {
+		path := themePath(name, builtinOnly)
+		if path == "" || seen[path] {
+			break
+		}
+		seen[path] = true
+		files = append(files, path)
+		tf, err := decodeCached(path)
 		if err != nil {
-			return "", fmt.Errorf("create temp file for embedded preset: %w", err)
+			break
 		}
-		if _, err := tmpFile.Write(data); err != nil {
-			tmpFile.Close()
-			return "", fmt.Errorf("write embedded preset to temp: %w", err)
+		builtinOnly = tf.Extends == name
+		name = tf.Extends
+	}
+	return files
+}
+
+// Names lists the built-in presets and user themes, sorted.
+func Names() []string {
+	seen := map[string]bool{}
+	for name := range presetMap {
+		seen[name] = true
+	}
+	if dir := userThemesDir(); dir != "" {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.toml"))
+		for _, m := range matches {
+			seen[strings.TrimSuffix(filepath.Base(m), ".toml")] = true
 		}
-		tmpFile.Close()
-		return tmpFile.Name(), nil
 	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-	return "", fmt.Errorf("preset file %q not found (looked in: %s)", file, strings.Join(candidates, ", "))
+// Encode renders t as a standalone theme file, with everything it extends
+// merged in so it can be shared on its own.
+func (t Theme) Encode() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Void theme %q\n", t.Name)
+	b.WriteString("[prompt]\n")
+	if t.Symbol != "" {
+		fmt.Fprintf(&b, "symbol = %s\n", config.FormatValue(t.Symbol))
+	}
+	if len(t.Segments) > 0 {
+		fmt.Fprintf(&b, "segments = %s\n", config.FormatValue(t.Segments))
+	}
+	keys := make([]string, 0, len(t.Palette))
+	for k := range t.Palette {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b.WriteString("\n[palette]\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s = %s\n", k, config.FormatValue(t.Palette[k]))
+	}
+	return []byte(b.String())
+}
+
+func presetCandidates(file string) []string {
+	candidates := []string{filepath.Join("presets", file)}
+	if exe, err := executablePath(); err == nil && strings.TrimSpace(exe) != "" {
+		exeCandidate := filepath.Join(filepath.Dir(exe), "presets", file)
+		if exeCandidate != candidates[0] {
+			candidates = append(candidates, exeCandidate)
+		}
+	}
+	return candidates
+}
+
+// cachedTheme is a decoded theme file with the size and modification time it
+// had when it was read.
+type cachedTheme struct {
+	tf      themeFile
+	size    int64
+	modTime time.Time
+}
+
+var (
+	cacheMu    sync.Mutex
+	themeCache = map[string]cachedTheme{}
+)
+
+// decodeEmbedded decodes a preset built into the binary straight from
+// presetFS. Embedded presets never change, so each is decoded once.
+func decodeEmbedded(file string) (themeFile, error) {
+	key := "embedded:" + file
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if c, ok := themeCache[key]; ok {
+		return c.tf, nil
+	}
+	var tf themeFile
+	if err := config.DecodeFS(presetFS, "presets/"+file, &tf); err != nil {
+		return tf, err
+	}
+	themeCache[key] = cachedTheme{tf: tf}
+	return tf, nil
+}
+
+// decodeCached decodes the theme file at path, reusing the previous result
+// while the file's size and modification time are unchanged.
+func decodeCached(path string) (themeFile, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return themeFile{}, err
+	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if c, ok := themeCache[path]; ok && c.size == fi.Size() && c.modTime.Equal(fi.ModTime()) {
+		return c.tf, nil
+	}
+	var tf themeFile
+	if err := config.DecodeFile(path, &tf); err != nil {
+		return tf, err
+	}
+	themeCache[path] = cachedTheme{tf: tf, size: fi.Size(), modTime: fi.ModTime()}
+	return tf, nil
 }
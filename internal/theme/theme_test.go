@@ -3,7 +3,10 @@ package theme
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/void-shell/void/internal/config"
 )
@@ -66,3 +69,192 @@ path_fg = "#abcdef"
 		t.Fatalf("expected preset palette to be applied, got %q", got.Palette["path_fg"])
 	}
 }
+
+func writeTheme(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+".toml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write theme %s: %v", name, err)
+	}
+}
+
+func useThemesDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	orig := userThemesDir
+	userThemesDir = func() string { return dir }
+	t.Cleanup(func() { userThemesDir = orig })
+	return dir
+}
+
+func TestUserThemeExtendsPreset(t *testing.T) {
+	dir := useThemesDir(t)
+	writeTheme(t, dir, "team", "extends = \"hacker\"\n[palette]\npath_fg = \"#123456\"\n")
+	writeTheme(t, dir, "late", "extends = \"team\"\n[prompt]\nsymbol = \"$\"\n")
+
+	got, err := Load("late")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	base, err := Load("hacker")
+	if err != nil {
+		t.Fatalf("load hacker: %v", err)
+	}
+	if got.Symbol != "$" || len(got.Segments) != len(base.Segments) {
+		t.Fatalf("unexpected prompt: %q %v", got.Symbol, got.Segments)
+	}
+	if got.Palette["path_fg"] != "#123456" || got.Palette["git_bg"] != base.Palette["git_bg"] {
+		t.Fatalf("unexpected palette: %v", got.Palette)
+	}
+	if files := Files("late"); len(files) < 2 || files[0] != filepath.Join(dir, "late.toml") || files[1] != filepath.Join(dir, "team.toml") {
+		t.Fatalf("unexpected files: %v", files)
+	}
+
+	cfg := config.Default()
+	cfg.Preset = "late"
+	applied, err := ApplyPreset(cfg)
+	if err != nil || applied.Prompt.Symbol != "$" {
+		t.Fatalf("ApplyPreset: %v %q", err, applied.Prompt.Symbol)
+	}
+
+	names := Names()
+	if len(names) != len(presetMap)+2 {
+		t.Fatalf("unexpected names: %v", names)
+	}
+}
+
+func TestUserThemeShadowingPresetExtendsBuiltIn(t *testing.T) {
+	dir := useThemesDir(t)
+	writeTheme(t, dir, "minimal", "extends = \"minimal\"\n[prompt]\nsymbol = \"%\"\n")
+
+	got, err := Load("minimal")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Symbol != "%" || len(got.Segments) == 0 {
+		t.Fatalf("expected built-in segments with user symbol, got %q %v", got.Symbol, got.Segments)
+	}
+}
+
+func TestThemeExtendsCycle(t *testing.T) {
+	dir := useThemesDir(t)
+	writeTheme(t, dir, "a", "extends = \"b\"\n")
+	writeTheme(t, dir, "b", "extends = \"a\"\n")
+
+	if _, err := Load("a"); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
+
+func TestThemeEncodeRoundTrip(t *testing.T) {
+	dir := useThemesDir(t)
+	in := Theme{Name: "shared", Symbol: "»", Segments: []string{"path", "git"}, Palette: map[string]string{"path_fg": "#ffffff", "git_bg": "#00ff00"}}
+	writeTheme(t, dir, "shared", string(in.Encode()))
+
+	out, err := Load("shared")
+	if err != nil {
+		t.Fatalf("load exported theme: %v", err)
+	}
+	if out.Symbol != in.Symbol || strings.Join(out.Segments, ",") != "path,git" || out.Palette["git_bg"] != "#00ff00" {
+		t.Fatalf("round trip mismatch: %#v", out)
+	}
+}
+
+func TestEmbeddedPresetLeavesNoTempFiles(t *testing.T) {
+	useThemesDir(t)
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TEMP", tmp)
+	t.Setenv("TMP", tmp)
+
+	origWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(origWD) })
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	origExecutablePath := executablePath
+	executablePath = func() (string, error) { return filepath.Join(t.TempDir(), "void"), nil }
+	t.Cleanup(func() { executablePath = origExecutablePath })
+
+	for i := 0; i < 3; i++ {
+		cfg := config.Default()
+		cfg.Preset = "hacker"
+		got, err := ApplyPreset(cfg)
+		if err != nil {
+			t.Fatalf("ApplyPreset: %v", err)
+		}
+		if got.Prompt.Symbol != "❯" {
+			t.Fatalf("expected embedded hacker symbol, got %q", got.Prompt.Symbol)
+		}
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Fatalf("expected no temp files, found %d", len(entries))
+	}
+}
+
+func TestCachedThemeReloadsWhenFileChanges(t *testing.T) {
+	dir := useThemesDir(t)
+	path := filepath.Join(dir, "mine.toml")
+	writeTheme(t, dir, "mine", "[prompt]\nsymbol = \"1\"\n")
+	first, err := Load("mine")
+	if err != nil || first.Symbol != "1" {
+		t.Fatalf("load: %v %q", err, first.Symbol)
+	}
+
+	writeTheme(t, dir, "mine", "[prompt]\nsymbol = \"22\"\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	second, err := Load("mine")
+	if err != nil || second.Symbol != "22" {
+		t.Fatalf("expected the edited theme, got %v %q", err, second.Symbol)
+	}
+}
+
+func TestApplyKeepsConfigPalette(t *testing.T) {
+	cfg := config.Default()
+	cfg.Palette = map[string]string{"user_bg": "#111111", "path_bg": "#222222"}
+	th := Theme{Symbol: "λ", Palette: map[string]string{"user_bg": "#333333"}}
+
+	got := th.Apply(cfg)
+	if got.Prompt.Symbol != "λ" || got.Palette["user_bg"] != "#333333" || got.Palette["path_bg"] != "#222222" {
+		t.Fatalf("expected the theme over the config, got symbol %q palette %v", got.Prompt.Symbol, got.Palette)
+	}
+	if cfg.Palette["user_bg"] != "#111111" {
+		t.Fatalf("expected the config palette to be left alone, got %v", cfg.Palette)
+	}
+}
+
+func TestUserSegmentsWinOverPreset(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TERMFORGE_CONFIG", "")
+	t.Setenv("APPDATA", "")
+	path := filepath.Join(home, "config.toml")
+	if err := os.WriteFile(path, []byte("preset = \"cyberpunk\"\n[prompt]\nsegments = [\"path\", \"go\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := config.LoadLayers(path, home)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	merged, err := ApplyPreset(r.Config)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := strings.Join(merged.Prompt.Segments, ","); got != "path,go" {
+		t.Fatalf("expected the configured segments, got %q", got)
+	}
+
+	// Without a list of its own, the config takes the preset's.
+	merged, err = ApplyPreset(config.Default())
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(merged.Prompt.Segments) == 0 || slices.Contains(merged.Prompt.Segments, "go") {
+		t.Fatalf("expected the preset's segments, got %v", merged.Prompt.Segments)
+	}
+}